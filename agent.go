@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	Interval      = "interval"
+	RefreshBefore = "before"
+	LogFile       = "log"
+	Once          = "once"
+
+	// minRoleSession is the shortest session AssumeRole gives, source session must last longer
+	minRoleSession = 15 * time.Minute
+)
+
+var AgentCommand = &cli.Command{
+	Name:   "agent",
+	Usage:  "keep role sessions fresh, re-assume roles before they expire with mfa session of their source profiles",
+	Action: agentAction,
+	Flags: []cli.Flag{
+		&cli.DurationFlag{
+			Name:  Interval,
+			Usage: "how often profiles are checked",
+			Value: time.Minute,
+		},
+		&cli.DurationFlag{
+			Name:  RefreshBefore,
+			Usage: "refresh role session when it expires within this duration",
+			Value: 10 * time.Minute,
+		},
+		&cli.StringFlag{
+			Name:  LogFile,
+			Usage: "log file, default is ~/.aws/aws-login/agent.log",
+		},
+		&cli.BoolFlag{
+			Name:  Once,
+			Usage: "check profiles once and exit",
+		},
+	},
+}
+
+// Agent refreshes role sessions whose source profile has a valid mfa session
+type Agent struct {
+	Before time.Duration
+	Logger *log.Logger
+
+	// last message of each profile, only changes are logged
+	last map[string]string
+}
+
+// logf logs message of profile if it is different from the last one
+func (ag *Agent) logf(profile string, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if ag.last[profile] == msg {
+		return
+	}
+	ag.last[profile] = msg
+	ag.Logger.Printf("[%s] %s", profile, msg)
+}
+
+// refreshFolder reads files of aws folder again and refreshes them, files may be changed between checks
+func (ag *Agent) refreshFolder(now time.Time) {
+	config, err := NewConfig(awsFoldPath)
+	if err != nil {
+		ag.Logger.Printf("%v", err)
+		return
+	}
+	ag.Refresh(config, now)
+}
+
+// Refresh checks all role profiles once, and re-assumes roles expiring soon
+func (ag *Agent) Refresh(config *Config, now time.Time) {
+	if ag.last == nil {
+		ag.last = make(map[string]string)
+	}
+	for _, p := range config.listManagedProfiles() {
+		if p.Kind != Role {
+			continue
+		}
+		if p.Expiry != nil && p.Expiry.Sub(now) > ag.Before {
+			ag.logf(p.Profile, "session valid until %s", p.Expiry.Local().Format(time.RFC3339))
+			continue
+		}
+
+		conf, err := config.LoadConfig(p.Profile)
+		if err != nil {
+			continue
+		}
+		input := &GetAssumeRoleRoleInput{
+			SourceProfile:    p.SourceProfile,
+			CredentialSource: p.CredentialSource,
+			AssumeRoleArn:    conf.AssumeRoleArn,
+			DurationSeconds:  conf.DurationSeconds,
+			Client:           globalClient.WithProfile(conf),
+		}
+		if p.CredentialSource != "" {
+			// credentials of source are renewed by its provider, only roles without mfa could be assumed unattended
+			if conf.SerialNumber != "" {
+				ag.logf(p.Profile, "role requires mfa, login %s again", p.Profile)
+				continue
+			}
+			ag.refresh(config, p.Profile, conf, input)
+			continue
+		}
+
+		source := &SessionCredential{}
+		if cred, err := config.LoadSession(p.SourceProfile); err == nil {
+			source = cred
+		}
+		if source.SessionToken == "" {
+			ag.logf(p.Profile, "source profile %q has no mfa session, login %s first", p.SourceProfile, p.SourceProfile)
+			continue
+		}
+		if !source.Expiration.IsZero() && source.Expiration.Sub(now) < minRoleSession {
+			ag.logf(p.Profile, "mfa session of source profile %q expires at %s, login %s again", p.SourceProfile, source.Expiration.Local().Format(time.RFC3339), p.SourceProfile)
+			continue
+		}
+
+		// the mfa session is already authenticated, no serial and code are needed
+		ag.refresh(config, p.Profile, conf, input)
+	}
+}
+
+// refresh assumes role of profile again and saves the session
+func (ag *Agent) refresh(config *Config, profile string, conf *ConfigData, input *GetAssumeRoleRoleInput) {
+	cred, err := aws.GetAssumeRoleSession(input)
+	if err != nil {
+		ag.logf(profile, "failed to assume role, %v", err)
+		return
+	}
+	if err := config.StoreSession(profile, conf, cred, false); err != nil {
+		ag.logf(profile, "failed to save session, %v", err)
+		return
+	}
+	ag.logf(profile, "refreshed, session valid until %s", cred.Expiration.Local().Format(time.RFC3339))
+}
+
+// openAgentLog opens log file for appending, with permission 0600
+func openAgentLog(path string) (*os.File, error) {
+	if path == "" {
+		path = filepath.Join(awsFoldPath, "aws-login", "agent.log")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+}
+
+func agentAction(c *cli.Context) error {
+	if c.Duration(Interval) <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	f, err := openAgentLog(c.String(LogFile))
+	if err != nil {
+		return fmt.Errorf("failed to open agent log, %w", err)
+	}
+	defer f.Close()
+	ag := &Agent{
+		Before: c.Duration(RefreshBefore),
+		Logger: log.New(io.MultiWriter(f, os.Stderr), "", log.LstdFlags),
+	}
+
+	ag.Logger.Printf("agent started, checking every %s", c.Duration(Interval))
+	ag.refreshFolder(time.Now())
+	if c.Bool(Once) {
+		return nil
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	ticker := time.NewTicker(c.Duration(Interval))
+	defer ticker.Stop()
+	for {
+		select {
+		case sig := <-stop:
+			ag.Logger.Printf("agent stopped by %s", sig)
+			return nil
+		case now := <-ticker.C:
+			ag.refreshFolder(now)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sixleaveakkm/aws-login/internal/awstest"
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/ini.v1"
+)
+
+func TestAgentRefresh(t *testing.T) {
+	now := time.Now()
+	dir := awstest.WriteFolder(t, `
+[profile dev]
+mfa_serial = arn:aws:iam::123456789012:mfa/user
+
+[profile admin]
+mfa_serial = arn:aws:iam::123456789012:mfa/user
+c_source_profile = dev
+c_role_arn = arn:aws:iam::210987654321:role/admin
+duration = 3600
+
+[profile fresh]
+c_source_profile = dev
+c_role_arn = arn:aws:iam::210987654321:role/fresh
+
+[profile ops]
+c_source_profile = nobody
+c_role_arn = arn:aws:iam::210987654321:role/ops
+`, `
+[dev]
+aws_access_key_id = SESSION_KEY
+aws_secret_access_key = SESSION_SECRET
+aws_session_token = TOKEN
+aws_expiration = `+now.Add(time.Hour).UTC().Format(time.RFC3339)+`
+
+[admin]
+aws_access_key_id = OLD_KEY
+aws_secret_access_key = OLD_SECRET
+aws_session_token = OLD_TOKEN
+aws_expiration = `+now.Add(5*time.Minute).UTC().Format(time.RFC3339)+`
+
+[fresh]
+aws_access_key_id = FRESH_KEY
+aws_secret_access_key = FRESH_SECRET
+aws_session_token = FRESH_TOKEN
+aws_expiration = `+now.Add(time.Hour).UTC().Format(time.RFC3339)+`
+`)
+	defer os.RemoveAll(dir)
+	output, restore := setOutputFolder(t)
+	defer restore()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := awslogin.NewMockAWS(ctrl)
+	aws = m
+	expiration := now.Add(time.Hour).Truncate(time.Second)
+	m.EXPECT().GetAssumeRoleSession(gomock.Any()).DoAndReturn(func(input *GetAssumeRoleRoleInput) (*SessionCredential, error) {
+		assert.Equal(t, "dev", input.SourceProfile)
+		assert.Equal(t, "arn:aws:iam::210987654321:role/admin", input.AssumeRoleArn)
+		assert.Equal(t, "", input.SerialNumber)
+		assert.Equal(t, int64(3600), input.DurationSeconds)
+		return &SessionCredential{AccessKey: "NEW_KEY", SecretKey: "NEW_SECRET", SessionToken: "NEW_TOKEN", Expiration: expiration}, nil
+	})
+
+	var logs bytes.Buffer
+	ag := &Agent{Before: 10 * time.Minute, Logger: log.New(&logs, "", 0)}
+	ag.Refresh(mustNewConfig(t, dir), now)
+
+	saved, err := ini.Load(filepath.Join(output, credentialsFile_))
+	assert.NoError(t, err)
+	var cred SessionCredential
+	assert.NoError(t, saved.Section("admin").MapTo(&cred))
+	assert.Equal(t, "NEW_TOKEN", cred.SessionToken)
+	assert.True(t, expiration.Equal(cred.Expiration))
+	assert.Contains(t, logs.String(), "[admin] refreshed")
+	assert.Contains(t, logs.String(), `[ops] source profile "nobody" has no mfa session`)
+
+	// same message is not logged again
+	logs.Reset()
+	ag.logf("ops", `source profile %q has no mfa session, login %s first`, "nobody", "nobody")
+	assert.Empty(t, logs.String())
+}
+
+func TestSaveCredentialLockedKeepsOtherSections(t *testing.T) {
+	dir := awstest.WriteFolder(t, "", "[dev]\naws_access_key_id = KEY\n")
+	defer os.RemoveAll(dir)
+	originalFolder := awsFoldPath
+	awsFoldPath = dir
+	debugging = false
+	defer func() {
+		awsFoldPath = originalFolder
+		debugging = true
+	}()
+
+	config := mustNewConfig(t, dir)
+	// saved by another process after config is loaded
+	path := filepath.Join(dir, credentialsFile_)
+	assert.NoError(t, ioutil.WriteFile(path, []byte("[dev]\naws_access_key_id = KEY\n\n[other]\naws_access_key_id = OTHER\n"), 0600))
+
+	assert.NoError(t, config.SaveCredentialLocked(&SessionCredential{AccessKey: "ROLE_KEY", SessionToken: "TOKEN"}, "admin"))
+	saved, err := ini.Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "OTHER", saved.Section("other").Key("aws_access_key_id").String())
+	assert.Equal(t, "ROLE_KEY", saved.Section("admin").Key("aws_access_key_id").String())
+	assert.Equal(t, "KEY", saved.Section("dev").Key("aws_access_key_id").String())
+}
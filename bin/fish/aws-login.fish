@@ -0,0 +1,9 @@
+# fish completion for aws-login, copy to ~/.config/fish/completions/aws-login.fish
+# or add "aws-login completion fish | source" to config.fish
+
+function __aws_login_complete
+    set -l args (commandline -opc)
+    env _CLI_FISH_AUTOCOMPLETE=1 $args --generate-bash-completion 2>/dev/null
+end
+
+complete -c aws-login -f -a '(__aws_login_complete)'
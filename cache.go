@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	Offline = "offline"
+
+	mfaCacheFile = "mfa_serials.json"
+	// MFACacheTTL is how long a discovered mfa serial is used without asking aws again
+	MFACacheTTL = 24 * time.Hour
+)
+
+var offlineFlag = &cli.BoolFlag{
+	Name:    Offline,
+	Usage:   "completion only uses cached mfa serials, aws api is never called",
+	EnvVars: []string{"AWS_LOGIN_OFFLINE"},
+}
+
+// mfaCacheEntry is the mfa serial found for a profile
+type mfaCacheEntry struct {
+	Serial    string    `json:"serial"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// cacheFilePath gets path of file in cache folder "<aws folder>/aws-login/cache"
+func cacheFilePath(name string) string {
+	folder := awsFoldPath
+	if debugging {
+		folder = debugOutputFolder
+	}
+	return filepath.Join(folder, "aws-login", "cache", name)
+}
+
+// readCacheJSON reads json file in cache folder into v, v is untouched if file doesn't exist or is broken
+func readCacheJSON(name string, v interface{}) {
+	content, err := ioutil.ReadFile(cacheFilePath(name))
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(content, v); err != nil {
+		debugf("ignoring broken cache %s, %v", name, err)
+	}
+}
+
+// writeCacheJSON saves v as json file in cache folder with permission 0600.
+// The cache is best effort, errors are only logged, nothing is written in dry run mode.
+func writeCacheJSON(name string, v interface{}) {
+	if dryRun {
+		return
+	}
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return
+	}
+	path := cacheFilePath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		debugf("failed to create cache folder, %v", err)
+		return
+	}
+	if err := writeSecretFile(path, content); err != nil {
+		debugf("failed to write cache %s, %v", name, err)
+	}
+}
+
+// readMFACache reads all cached mfa serials, empty if cache doesn't exist or is broken
+func readMFACache() map[string]mfaCacheEntry {
+	entries := make(map[string]mfaCacheEntry)
+	readCacheJSON(mfaCacheFile, &entries)
+	return entries
+}
+
+// writeMFACache saves mfa serial of profile
+func writeMFACache(profile string, serial string) {
+	if serial == "" || serial == awslogin.MFAPrefix {
+		return
+	}
+	entries := readMFACache()
+	entries[profile] = mfaCacheEntry{Serial: serial, UpdatedAt: time.Now()}
+	writeCacheJSON(mfaCacheFile, entries)
+}
+
+// cachedMFAString gets mfa serial of profile for completion.
+// A fresh cached serial is used as is, otherwise aws is asked and the result cached.
+// If aws could not answer, a stale serial is still better than the prefix.
+// In offline mode, aws is never asked.
+func cachedMFAString(profile string, client ClientConfig, offline bool) string {
+	entry, ok := readMFACache()[profile]
+	if ok && (offline || time.Since(entry.UpdatedAt) < MFACacheTTL) {
+		return entry.Serial
+	}
+	if offline {
+		return awslogin.MFAPrefix
+	}
+	serial := aws.GetMFAString(profile, client)
+	if serial == awslogin.MFAPrefix {
+		if ok {
+			return entry.Serial
+		}
+		return serial
+	}
+	writeMFACache(profile, serial)
+	return serial
+}
+
+// warmMFACache records serial used by a successful login, so completion finds it without asking aws.
+// The serial belongs to the user of source profile for role profiles.
+// Without a configured serial, it is discovered from aws in background, bounded by the timeout of GetMFAString.
+// Call the returned func to wait for discovery before exiting.
+func warmMFACache(profile string, conf *ConfigData, client ClientConfig) (wait func()) {
+	wait = func() {}
+	if conf.SourceProfile != "" {
+		profile = conf.SourceProfile
+	}
+	if conf.SerialNumber != "" {
+		writeMFACache(profile, conf.SerialNumber)
+		return wait
+	}
+	// credentials of credential source belong to no user
+	if conf.CredentialSource != "" {
+		return wait
+	}
+	if entry, ok := readMFACache()[profile]; ok && time.Since(entry.UpdatedAt) < MFACacheTTL {
+		return wait
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		writeMFACache(profile, aws.GetMFAString(profile, client))
+	}()
+	return func() { <-done }
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachedMFAString(t *testing.T) {
+	_ = os.Remove(cacheFilePath(mfaCacheFile))
+	defer os.Remove(cacheFilePath(mfaCacheFile))
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := awslogin.NewMockAWS(ctrl)
+	aws = m
+
+	// offline without cache never asks aws
+	assert.Equal(t, awslogin.MFAPrefix, cachedMFAString("dev", ClientConfig{}, true))
+
+	// aws is asked once, then the cache is used
+	m.EXPECT().GetMFAString("dev", gomock.Any()).Return("arn:aws:iam::123456789012:mfa/user").Times(1)
+	assert.Equal(t, "arn:aws:iam::123456789012:mfa/user", cachedMFAString("dev", ClientConfig{}, false))
+	assert.Equal(t, "arn:aws:iam::123456789012:mfa/user", cachedMFAString("dev", ClientConfig{}, false))
+	assert.Equal(t, "arn:aws:iam::123456789012:mfa/user", cachedMFAString("dev", ClientConfig{}, true))
+
+	// failed lookup is not cached
+	m.EXPECT().GetMFAString("other", gomock.Any()).Return(awslogin.MFAPrefix).Times(2)
+	assert.Equal(t, awslogin.MFAPrefix, cachedMFAString("other", ClientConfig{}, false))
+	assert.Equal(t, awslogin.MFAPrefix, cachedMFAString("other", ClientConfig{}, false))
+}
+
+func TestWarmMFACache(t *testing.T) {
+	_ = os.Remove(cacheFilePath(mfaCacheFile))
+	defer os.Remove(cacheFilePath(mfaCacheFile))
+
+	warmMFACache("admin", &ConfigData{SerialNumber: "arn:aws:iam::123456789012:mfa/user", SourceProfile: "dev"}, ClientConfig{})()
+	entries := readMFACache()
+	assert.Equal(t, "arn:aws:iam::123456789012:mfa/user", entries["dev"].Serial)
+	assert.NotContains(t, entries, "admin")
+
+	// serial of source profile without mfa_serial is discovered
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := awslogin.NewMockAWS(ctrl)
+	aws = m
+	m.EXPECT().GetMFAString("ops", gomock.Any()).Return("arn:aws:iam::123456789012:mfa/ops").Times(1)
+	warmMFACache("deploy", &ConfigData{AssumeRoleArn: "arn:aws:iam::210987654321:role/deploy", SourceProfile: "ops"}, ClientConfig{})()
+	assert.Equal(t, "arn:aws:iam::123456789012:mfa/ops", readMFACache()["ops"].Serial)
+	// fresh serial is not asked again, and credential source has no user to ask
+	warmMFACache("deploy", &ConfigData{AssumeRoleArn: "arn:aws:iam::210987654321:role/deploy", SourceProfile: "ops"}, ClientConfig{})()
+	warmMFACache("ci", &ConfigData{AssumeRoleArn: "arn:aws:iam::210987654321:role/deploy", CredentialSource: "Environment"}, ClientConfig{})()
+}
@@ -9,6 +9,13 @@ import (
 	"github.com/urfave/cli/v2"
 )
 
+var CompletionCommand = &cli.Command{
+	Name:      "completion",
+	Usage:     "print completion script of shell",
+	ArgsUsage: "bash|zsh|fish",
+	Action:    completionAction,
+}
+
 const (
 	TextGenerateConfig = "generate new config item"
 	TextProfile        = "login use profile"
@@ -49,12 +56,24 @@ func getLastArgument(level int) string {
 	return args[l-1]
 }
 
+// FishCompleteEnv is set by fish completion script, values are printed in fish format then
+const FishCompleteEnv = "_CLI_FISH_AUTOCOMPLETE"
+
 // printWithExplain makes input into value explain pair.
 // It will print following prompt results when completion:
 //
 //     some result     | explanation for "some result"
 //
+// For fish, pair is separated by tab and value is not escaped.
 func printWithExplain(v string, e string) {
+	if os.Getenv(FishCompleteEnv) != "" {
+		if e == "" {
+			fmt.Println(v)
+		} else {
+			fmt.Printf("%s\t%s\n", v, e)
+		}
+		return
+	}
 	escapedV := strings.Replace(v, ":", "\\:", -1)
 	escapedV = strings.Replace(escapedV, " ", "\\ ", -1)
 	if e == "" {
@@ -76,7 +95,11 @@ func loginBashComplete(c *cli.Context) {
 	}
 
 	if last == "-p" || last == "--profile" {
-		for k, v := range NewConfig(awsFoldPath).listMFAProfiles() {
+		config, err := NewConfig(awsFoldPath)
+		if err != nil {
+			return
+		}
+		for k, v := range config.listMFAProfiles() {
 			printWithExplain(k, v)
 		}
 		return
@@ -116,7 +139,11 @@ func configBashComplete(_ *cli.Context) {
 func configMFABashComplete(c *cli.Context) {
 	last := getLastArgument(2)
 	if last == "-p" || last == "--profile" {
-		for p := range NewConfig(awsFoldPath).listPossibleProfiles().Iter() {
+		config, err := NewConfig(awsFoldPath)
+		if err != nil {
+			return
+		}
+		for p := range config.ListPossibleProfiles().Iter() {
 			printWithExplain(p.(string), "")
 		}
 		return
@@ -132,8 +159,14 @@ func configMFABashComplete(c *cli.Context) {
 	if last == "-n" || last == fmt.Sprintf("--%s", SerialNumber) {
 		if flagSet.Contains("profile") {
 			p := c.String("profile")
+			loadGlobalClientConfig(c)
+			config, err := NewConfig(awsFoldPath)
+			if err != nil {
+				return
+			}
+			client := config.clientConfigFor(p)
 			// todo: possible session timeout if config same profile name twice
-			printWithExplain(aws.GetMFAString(p), "mfa string or prefix for given profile")
+			printWithExplain(cachedMFAString(p, client, c.Bool(Offline)), "mfa string or prefix for given profile")
 		}
 		return
 	}
@@ -170,8 +203,12 @@ func configMFABashComplete(c *cli.Context) {
 func configRoleBashComplete(c *cli.Context) {
 	last := getLastArgument(2)
 	if last == "-s" || last == "--source-profile" {
-		for p := range NewConfig(awsFoldPath).listPossibleProfiles().Iter() {
-			fmt.Println(strings.ReplaceAll(p.(string), " ", "\\ "))
+		config, err := NewConfig(awsFoldPath)
+		if err != nil {
+			return
+		}
+		for p := range config.ListPossibleProfiles().Iter() {
+			printWithExplain(p.(string), "")
 		}
 		return
 	}
@@ -184,8 +221,14 @@ func configRoleBashComplete(c *cli.Context) {
 	if last == "-n" || last == fmt.Sprintf("--%s", SerialNumber) {
 		if flagSet.Contains(SourceProfile) {
 			p := c.String(SourceProfile)
+			loadGlobalClientConfig(c)
+			config, err := NewConfig(awsFoldPath)
+			if err != nil {
+				return
+			}
+			client := config.clientConfigFor(p)
 			// todo: possible session timeout if config same profile name twice
-			printWithExplain(aws.GetMFAString(p), "mfa string or prefix for given profile")
+			printWithExplain(cachedMFAString(p, client, c.Bool(Offline)), "mfa string or prefix for given profile")
 		}
 		return
 	}
@@ -244,3 +287,21 @@ func configRoleBashComplete(c *cli.Context) {
 	}
 
 }
+
+func completionAction(c *cli.Context) error {
+	shell, err := shellOf(c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	fmt.Print(completionScripts[shell])
+	return nil
+}
+
+//go:generate go run scripts/gen_completion.go
+
+// completionScripts are generated from ones in bin/, go 1.13 could not embed files
+var completionScripts = map[string]string{
+	ShellBash: bashCompletion,
+	ShellZsh:  zshCompletion,
+	ShellFish: fishCompletion,
+}
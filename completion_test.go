@@ -0,0 +1,90 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sixleaveakkm/aws-login/internal/awstest"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCompletionScriptsMatchBin fails when bin/ is changed without running `go generate`
+func TestCompletionScriptsMatchBin(t *testing.T) {
+	for path, script := range map[string]string{
+		filepath.Join("bin", "bash", "_aws-login"):     bashCompletion,
+		filepath.Join("bin", "zsh", "_aws-login"):      zshCompletion,
+		filepath.Join("bin", "fish", "aws-login.fish"): fishCompletion,
+	} {
+		content, err := ioutil.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, string(content), script, path+" changed, run go generate")
+	}
+}
+
+// captureStdout gets what f prints to stdout, completions are printed there
+func captureStdout(t *testing.T, f func()) string {
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	f()
+	_ = w.Close()
+	out, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	return string(out)
+}
+
+// complete runs completion of args as shells do
+func complete(t *testing.T, args ...string) string {
+	original := os.Args
+	os.Args = append(append([]string{"aws-login"}, args...), "--generate-bash-completion")
+	defer func() { os.Args = original }()
+	return captureStdout(t, func() { executor(os.Args) })
+}
+
+func TestConfigRoleCompletion(t *testing.T) {
+	dir := awstest.WriteFolder(t, "[profile team:dev ops]\nregion = us-east-1\n", "")
+	defer os.RemoveAll(dir)
+	originalFolder := awsFoldPath
+	awsFoldPath = dir
+	defer func() { awsFoldPath = originalFolder }()
+
+	// flags are checked by one line, profiles by the whole output
+	tests := []struct {
+		name     string
+		fish     bool
+		args     []string
+		want     string
+		wantLine bool
+	}{
+		{"bash flags", false, []string{"config", "role"}, "-s:" + TextConfRoleSourceProfile + "\n", true},
+		{"bash profiles", false, []string{"config", "role", "-s"}, "team\\:dev\\ ops\n", false},
+		{"fish flags", true, []string{"config", "role"}, "-s\t" + TextConfRoleSourceProfile + "\n", true},
+		{"fish profiles", true, []string{"config", "role", "-s"}, "team:dev ops\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.fish {
+				_ = os.Setenv(FishCompleteEnv, "1")
+				defer os.Unsetenv(FishCompleteEnv)
+			}
+			out := complete(t, tt.args...)
+			if tt.wantLine {
+				assert.Contains(t, out, tt.want)
+			} else {
+				assert.Equal(t, tt.want, out)
+			}
+		})
+	}
+}
+
+func TestFishCompletionDescriptions(t *testing.T) {
+	_ = os.Setenv(FishCompleteEnv, "1")
+	defer os.Unsetenv(FishCompleteEnv)
+
+	out := complete(t, "config")
+	assert.Equal(t, MFA+"\t"+TextConfigMFA+"\n"+Role+"\t"+TextConfigRole+"\n", out)
+}
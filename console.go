@@ -0,0 +1,218 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	Service            = "service"
+	Print              = "print"
+	FederationEndpoint = "federation-endpoint"
+	ConsoleURL         = "console-url"
+
+	DefaultFederationEndpoint = "https://signin.aws.amazon.com/federation"
+	DefaultConsoleURL         = "https://console.aws.amazon.com/"
+	// DefaultConsoleDuration 1 hour, console session could be 15 minutes to 12 hours
+	DefaultConsoleDuration = 3600
+)
+
+var ConsoleCommand = &cli.Command{
+	Name:   "console",
+	Usage:  "open aws console signed in with session of role profile",
+	Action: consoleAction,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    Profile,
+			Aliases: []string{"p"},
+			Usage:   "role profile to sign in",
+		},
+		&cli.StringFlag{
+			Name:    Service,
+			Aliases: []string{"s"},
+			Usage:   "console page to open, a service like s3 or a full console url",
+		},
+		&cli.BoolFlag{
+			Name:  Print,
+			Usage: "print sign-in url instead of opening browser",
+		},
+		&cli.Int64Flag{
+			Name:    Duration,
+			Aliases: []string{"t"},
+			Usage:   "console session duration in seconds, from 900 to 43200, not longer than the role session",
+			Value:   DefaultConsoleDuration,
+		},
+		&cli.StringFlag{
+			Name:    FederationEndpoint,
+			Usage:   "federation endpoint exchanging session for sign-in token",
+			Value:   DefaultFederationEndpoint,
+			EnvVars: []string{"AWS_LOGIN_FEDERATION_ENDPOINT"},
+		},
+		&cli.StringFlag{
+			Name:    ConsoleURL,
+			Usage:   "console url services are opened under",
+			Value:   DefaultConsoleURL,
+			EnvVars: []string{"AWS_LOGIN_CONSOLE_URL"},
+		},
+	},
+}
+
+// ConsoleResult is printed when sign-in url is printed in json mode
+type ConsoleResult struct {
+	Profile string `json:"profile"`
+	URL     string `json:"url"`
+}
+
+// consoleDestination gets url of console page for service, full urls are used as is
+func consoleDestination(consoleURL string, service string, region string) string {
+	if strings.HasPrefix(service, "https://") || strings.HasPrefix(service, "http://") {
+		return service
+	}
+	destination := strings.TrimSuffix(consoleURL, "/") + "/"
+	if service != "" {
+		destination += url.PathEscape(service) + "/home"
+	}
+	if region != "" {
+		destination += "?region=" + url.QueryEscape(region)
+	}
+	return destination
+}
+
+// getSigninToken exchanges session credential for sign-in token at federation endpoint
+func getSigninToken(httpClient *http.Client, endpoint string, cred *SessionCredential, duration int64) (string, error) {
+	session, _ := json.Marshal(map[string]string{
+		"sessionId":    cred.AccessKey,
+		"sessionKey":   cred.SecretKey,
+		"sessionToken": cred.SessionToken,
+	})
+	query := url.Values{
+		"Action":          {"getSigninToken"},
+		"SessionDuration": {strconv.FormatInt(duration, 10)},
+		"Session":         {string(session)},
+	}
+	debugf("requesting sign-in token from %s", endpoint)
+	resp, err := httpClient.Get(endpoint + "?" + query.Encode())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("federation endpoint returned %s", resp.Status)
+	}
+	var token struct {
+		SigninToken string
+	}
+	if err := json.Unmarshal(body, &token); err != nil || token.SigninToken == "" {
+		return "", fmt.Errorf("federation endpoint returned no sign-in token")
+	}
+	return token.SigninToken, nil
+}
+
+// signinURL makes url signing in console with token and opening destination
+func signinURL(endpoint string, token string, destination string) string {
+	query := url.Values{
+		"Action":      {"login"},
+		"Issuer":      {"aws-login"},
+		"Destination": {destination},
+		"SigninToken": {token},
+	}
+	return endpoint + "?" + query.Encode()
+}
+
+// federationHTTPClient makes http client with proxy, timeout and ca bundle of client settings
+func federationHTTPClient(client ClientConfig) (*http.Client, error) {
+	httpClient, err := awslogin.NewHTTPClient(client)
+	if err != nil {
+		return nil, err
+	}
+	if client.CABundle == "" {
+		return httpClient, nil
+	}
+	pem, err := ioutil.ReadFile(client.CABundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ca bundle, %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in ca bundle %s", client.CABundle)
+	}
+	httpClient.Transport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: pool}
+	return httpClient, nil
+}
+
+// openBrowser opens url with the default browser of the system
+func openBrowser(u string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", u).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", u).Start()
+	default:
+		return exec.Command("xdg-open", u).Start()
+	}
+}
+
+func consoleAction(c *cli.Context) error {
+	profile := c.String(Profile)
+	if profile == "" {
+		profile = getProfile(c)
+	}
+	duration := c.Int64(Duration)
+	if duration < 900 || duration > 43200 {
+		return fmt.Errorf("duration must be from 900 to 43200 seconds, got %d", duration)
+	}
+
+	config, err := NewConfig(awsFoldPath)
+	if err != nil {
+		return err
+	}
+	if conf, err := config.LoadConfig(profile); err == nil && !conf.IsRole() && conf.SerialNumber != "" {
+		return fmt.Errorf("%q is a mfa profile, console sign-in needs session of a role profile", profile)
+	}
+	data, err := loadExportData(config, profile)
+	if err != nil {
+		return err
+	}
+	httpClient, err := federationHTTPClient(config.clientConfigFor(profile))
+	if err != nil {
+		return err
+	}
+	endpoint := c.String(FederationEndpoint)
+	token, err := getSigninToken(httpClient, endpoint, data.Credential, duration)
+	if err != nil {
+		return awslogin.NewLoginError(profile, "failed to get sign-in token", err, false)
+	}
+	u := signinURL(endpoint, token, consoleDestination(c.String(ConsoleURL), c.String(Service), data.Region))
+
+	if outputFormat == OutputJSON {
+		printJSON(&ConsoleResult{Profile: profile, URL: u})
+		return nil
+	}
+	if c.Bool(Print) {
+		fmt.Println(u)
+		return nil
+	}
+	if err := openBrowser(u); err != nil {
+		debugf("failed to open browser, %v", err)
+		fmt.Println(u)
+		return nil
+	}
+	fmt.Fprintf(infoOut(), "opened console of %s in browser\n", profile)
+	return nil
+}
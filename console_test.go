@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsoleDestination(t *testing.T) {
+	assert.Equal(t, "https://console.aws.amazon.com/s3/home?region=us-east-1", consoleDestination(DefaultConsoleURL, "s3", "us-east-1"))
+	assert.Equal(t, "https://console.aws.amazon.com/", consoleDestination(DefaultConsoleURL, "", ""))
+	assert.Equal(t, "https://example.com/x", consoleDestination(DefaultConsoleURL, "https://example.com/x", "us-east-1"))
+}
+
+func TestGetSigninToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		assert.Equal(t, "getSigninToken", query.Get("Action"))
+		assert.Equal(t, "1800", query.Get("SessionDuration"))
+		var session map[string]string
+		assert.NoError(t, json.Unmarshal([]byte(query.Get("Session")), &session))
+		assert.Equal(t, "KEY", session["sessionId"])
+		assert.Equal(t, "SECRET", session["sessionKey"])
+		assert.Equal(t, "TOKEN", session["sessionToken"])
+		_, _ = w.Write([]byte(`{"SigninToken":"SIGNIN"}`))
+	}))
+	defer server.Close()
+
+	token, err := getSigninToken(server.Client(), server.URL, &SessionCredential{AccessKey: "KEY", SecretKey: "SECRET", SessionToken: "TOKEN"}, 1800)
+	assert.NoError(t, err)
+	assert.Equal(t, "SIGNIN", token)
+
+	u, err := url.Parse(signinURL(server.URL, token, "https://console.aws.amazon.com/"))
+	assert.NoError(t, err)
+	assert.Equal(t, "login", u.Query().Get("Action"))
+	assert.Equal(t, "SIGNIN", u.Query().Get("SigninToken"))
+	assert.Equal(t, "https://console.aws.amazon.com/", u.Query().Get("Destination"))
+}
+
+func TestGetSigninTokenRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	_, err := getSigninToken(server.Client(), server.URL, &SessionCredential{}, 900)
+	assert.Error(t, err)
+}
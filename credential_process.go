@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+const CacheOnly = "cache-only"
+
+// cacheOnly saves sessions to aws cli cache instead of credentials file
+var cacheOnly bool
+
+var cacheOnlyFlag = &cli.BoolFlag{
+	Name:    CacheOnly,
+	Usage:   "save sessions to aws cli cache (~/.aws/cli/cache) instead of credentials file, profiles get credential_process reading it",
+	EnvVars: []string{"AWS_LOGIN_CACHE_ONLY"},
+}
+
+var CredentialProcessCommand = &cli.Command{
+	Name:   "credential-process",
+	Usage:  "print session of profile for credential_process of aws sdks, set to cache-only profiles by login",
+	Action: credentialProcessAction,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     Profile,
+			Aliases:  []string{"p"},
+			Usage:    "profile whose session is printed",
+			Required: true,
+		},
+	},
+}
+
+var EnvCommand = &cli.Command{
+	Name:   "env",
+	Usage:  "print commands exporting session of profile, e.g. eval \"$(aws-login env -p dev)\"",
+	Action: envAction,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     Profile,
+			Aliases:  []string{"p"},
+			Usage:    "profile whose session is exported",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  Shell,
+			Usage: "one of bash, zsh, fish, detected from SHELL env if not given",
+		},
+	},
+}
+
+var ExecCommand = &cli.Command{
+	Name:      "exec",
+	Usage:     "run command with session of profile in environment variables",
+	ArgsUsage: "-- <command> [args...]",
+	Action:    execAction,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     Profile,
+			Aliases:  []string{"p"},
+			Usage:    "profile whose session is used",
+			Required: true,
+		},
+	},
+}
+
+// sessionEnvNames are environments of credentials, replaced by exec so the command uses only the session given
+var sessionEnvNames = []string{
+	"AWS_ACCESS_KEY_ID",
+	"AWS_SECRET_ACCESS_KEY",
+	"AWS_SESSION_TOKEN",
+	"AWS_SECURITY_TOKEN",
+	"AWS_CREDENTIAL_EXPIRATION",
+	"AWS_PROFILE",
+	"AWS_DEFAULT_PROFILE",
+}
+
+// writeCredentialProcess writes session in the format credential_process outputs
+func writeCredentialProcess(w io.Writer, d *ExportData) {
+	data := struct {
+		Version         int    `json:"Version"`
+		AccessKeyId     string `json:"AccessKeyId"`
+		SecretAccessKey string `json:"SecretAccessKey"`
+		SessionToken    string `json:"SessionToken,omitempty"`
+		Expiration      string `json:"Expiration,omitempty"`
+	}{1, d.Credential.AccessKey, d.Credential.SecretKey, d.Credential.SessionToken, d.expiration()}
+	var buf bytes.Buffer
+	_ = json.NewEncoder(&buf).Encode(data)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// writeEnv writes commands exporting session environments in shell
+func writeEnv(w io.Writer, shell string, d *ExportData) {
+	for _, p := range d.envPairs() {
+		if shell == ShellFish {
+			fmt.Fprintf(w, "set -gx %s %s\n", p[0], quoteFor(shell, p[1]))
+		} else {
+			fmt.Fprintf(w, "export %s=%s\n", p[0], quoteFor(shell, p[1]))
+		}
+	}
+}
+
+// execEnv is environ with session environments of d replacing credentials set before
+func execEnv(environ []string, d *ExportData) []string {
+	env := make([]string, 0, len(environ))
+	for _, kv := range environ {
+		name := strings.SplitN(kv, "=", 2)[0]
+		replaced := false
+		for _, n := range sessionEnvNames {
+			if name == n {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			env = append(env, kv)
+		}
+	}
+	for _, p := range d.envPairs() {
+		env = append(env, p[0]+"="+p[1])
+	}
+	return env
+}
+
+func credentialProcessAction(c *cli.Context) error {
+	config, err := NewConfig(awsFoldPath)
+	if err != nil {
+		return err
+	}
+	data, err := loadExportData(config, c.String(Profile))
+	if err != nil {
+		return err
+	}
+	writeCredentialProcess(os.Stdout, data)
+	return nil
+}
+
+func envAction(c *cli.Context) error {
+	shell, err := shellOf(c.String(Shell))
+	if err != nil {
+		return err
+	}
+	config, err := NewConfig(awsFoldPath)
+	if err != nil {
+		return err
+	}
+	data, err := loadExportData(config, c.String(Profile))
+	if err != nil {
+		return err
+	}
+	writeEnv(os.Stdout, shell, data)
+	return nil
+}
+
+func execAction(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return fmt.Errorf("command is required, e.g. aws-login exec -p dev -- aws s3 ls")
+	}
+	config, err := NewConfig(awsFoldPath)
+	if err != nil {
+		return err
+	}
+	data, err := loadExportData(config, c.String(Profile))
+	if err != nil {
+		return err
+	}
+	args := c.Args().Slice()
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = execEnv(os.Environ(), data)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// exit with the code of command, its output explains the failure
+		return cli.Exit("", exitErr.ExitCode())
+	}
+	return err
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCredentialProcessOutputs(t *testing.T) {
+	data := &ExportData{
+		Profile: "dev",
+		Region:  "us-east-1",
+		Credential: &SessionCredential{
+			AccessKey:    "KEY",
+			SecretKey:    "SECRET",
+			SessionToken: "TOKEN",
+			Expiration:   time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	var buf bytes.Buffer
+	writeCredentialProcess(&buf, data)
+	assert.Equal(t, `{"Version":1,"AccessKeyId":"KEY","SecretAccessKey":"SECRET","SessionToken":"TOKEN","Expiration":"2030-01-01T00:00:00Z"}`+"\n", buf.String())
+
+	buf.Reset()
+	writeEnv(&buf, ShellFish, data)
+	assert.Contains(t, buf.String(), "set -gx AWS_SESSION_TOKEN 'TOKEN'\n")
+	buf.Reset()
+	writeEnv(&buf, ShellBash, data)
+	assert.Contains(t, buf.String(), "export AWS_SESSION_TOKEN='TOKEN'\n")
+
+	env := execEnv([]string{"HOME=/root", "AWS_PROFILE=prod", "AWS_SESSION_TOKEN=OLD"}, data)
+	assert.Contains(t, env, "HOME=/root")
+	assert.Contains(t, env, "AWS_SESSION_TOKEN=TOKEN")
+	assert.NotContains(t, env, "AWS_PROFILE=prod")
+	assert.NotContains(t, env, "AWS_SESSION_TOKEN=OLD")
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+const Debug = "debug"
+
+// debugLog enables debug logs to debugOut.
+// Not to be confused with `debugging`, which redirects saved files for tests.
+var (
+	debugLog bool
+	debugOut io.Writer = os.Stderr
+)
+
+var debugFlag = &cli.BoolFlag{
+	Name:    Debug,
+	Usage:   "print debug logs to stderr, secrets are redacted",
+	EnvVars: []string{"AWS_LOGIN_DEBUG"},
+}
+
+// sensitiveNames are names of keys, parameters and headers whose values are never logged
+var sensitiveNames = []string{
+	"aws_secret_access_key",
+	"aws_session_token",
+	"SecretAccessKey",
+	"SessionToken",
+	"sessionKey",
+	"TokenCode",
+	"AuthenticationCode1",
+	"AuthenticationCode2",
+	"X-Amz-Security-Token",
+	"X-Amz-Signature",
+	"Signature",
+	"SigninToken",
+	"Base32StringSeed",
+	"QRCodePNG",
+}
+
+const redacted = "<redacted>"
+
+var redactPatterns = func() []*regexp.Regexp {
+	names := strings.Join(sensitiveNames, "|")
+	return []*regexp.Regexp{
+		// xml: <SessionToken>value</SessionToken>
+		regexp.MustCompile(`(?i)(<(?:` + names + `)>)[^<]*`),
+		// json: "SessionToken": "value"
+		regexp.MustCompile(`(?i)("(?:` + names + `)"\s*:\s*")[^"]*`),
+		// ini, form, query, header: SessionToken = value, TokenCode=value&, X-Amz-Security-Token: value
+		regexp.MustCompile(`(?i)(\b(?:` + names + `)\s*[=:]\s*)[^\s&,;"]+`),
+	}
+}()
+
+// redact replaces values of sensitive names in s
+func redact(s string) string {
+	for _, p := range redactPatterns {
+		s = p.ReplaceAllString(s, "${1}"+redacted)
+	}
+	return s
+}
+
+// debugf prints redacted debug log to stderr if debug is enabled
+func debugf(format string, args ...interface{}) {
+	if !debugLog {
+		return
+	}
+	msg := redact(fmt.Sprintf(format, args...))
+	fmt.Fprintln(debugOut, a.Gray(12, "[debug] "+msg))
+}
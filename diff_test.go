@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sixleaveakkm/aws-login/internal/awstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_unifiedDiff(t *testing.T) {
+	assert.Equal(t, "", unifiedDiff("a", "b", "x\ny\n", "x\ny\n"))
+
+	before := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n"
+	after := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n"
+	assert.Equal(t, `--- a
++++ b
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+@@ -13,3 +13,4 @@
+ 13
+ 14
+ 15
++16
+`, unifiedDiff("a", "b", before, after))
+
+	assert.Equal(t, "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n", unifiedDiff("a", "b", "", "x\ny\n"))
+}
+
+func TestDryRunKeepsFiles(t *testing.T) {
+	config := "[profile dev]\nregion = us-east-1\n"
+	credentials := "[dev]\naws_access_key_id = KEY\naws_secret_access_key = SECRET\n"
+	dir := awstest.WriteFolder(t, config, credentials)
+	defer os.RemoveAll(dir)
+
+	dryRun = true
+	defer func() { dryRun, dryRunConfigs = false, nil }()
+	originalFolder := awsFoldPath
+	awsFoldPath = dir
+	defer func() { awsFoldPath = originalFolder }()
+
+	c := mustNewConfig(t, dir)
+	assert.NoError(t, c.BackupNoMFACredential("dev"))
+	assert.NoError(t, c.SaveConfig(&ConfigData{Region: "us-east-1", SerialNumber: "arn:mfa", DurationSeconds: 3600}, "dev"))
+	assert.NoError(t, c.SaveCredential(&SessionCredential{AccessKey: "SESSION_KEY", SecretKey: "SESSION_SECRET", SessionToken: "TOKEN"}, "dev"))
+
+	savedConfig, _ := ioutil.ReadFile(filepath.Join(dir, configFile_))
+	savedCredentials, _ := ioutil.ReadFile(filepath.Join(dir, credentialsFile_))
+	assert.Equal(t, config, string(savedConfig))
+	assert.Equal(t, credentials, string(savedCredentials))
+
+	diff := c.pendingDiff()
+	assert.Contains(t, diff, "+mfa_serial = arn:mfa")
+	assert.Contains(t, diff, "+[dev_no_mfa]")
+	assert.Contains(t, diff, "+aws_session_token = <redacted>")
+	assert.NotContains(t, diff, "SECRET")
+	assert.NotContains(t, diff, "TOKEN")
+}
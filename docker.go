@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	// DockerHelperName is the name docker runs aws-login as, when "aws-login" is set in credHelpers
+	DockerHelperName = "docker-credential-aws-login"
+
+	ecrTokensFile = "ecr_tokens.json"
+	// ecrTokenMargin renews cached tokens before they expire, so a pull started doesn't fail halfway
+	ecrTokenMargin = 10 * time.Minute
+)
+
+// errDockerNotFound is the message docker treats as no credentials for registry, it pulls anonymously then
+var errDockerNotFound = errors.New("credentials not found in native keychain")
+
+var ecrRegistryHost = regexp.MustCompile(`^\d{12}\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?$`)
+
+var DockerCredentialCommand = &cli.Command{
+	Name:      "docker-credential",
+	Usage:     "docker credential helper of ecr registries in c_docker_registries of profiles",
+	ArgsUsage: "get|store|erase|list",
+	Action:    dockerCredentialAction,
+}
+
+// dockerCredential is the credential of registry in docker credential helper protocol
+type dockerCredential struct {
+	ServerURL string `json:"ServerURL"`
+	Username  string `json:"Username"`
+	Secret    string `json:"Secret"`
+}
+
+// ecrTokenEntry is a cached docker login of registry
+type ecrTokenEntry struct {
+	Profile   string    `json:"profile"`
+	Username  string    `json:"username"`
+	Password  string    `json:"password"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// dockerHelperArgs turns args of docker running aws-login as docker-credential-aws-login into the command
+func dockerHelperArgs(args []string) []string {
+	name := strings.TrimSuffix(filepath.Base(args[0]), ".exe")
+	if name != DockerHelperName {
+		return args
+	}
+	return append([]string{args[0], DockerCredentialCommand.Name}, args[1:]...)
+}
+
+// registryHost gets host of registry from server url given by docker
+func registryHost(serverURL string) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(serverURL), "https://"), "http://")
+	if i := strings.IndexByte(host, '/'); i >= 0 {
+		host = host[:i]
+	}
+	return strings.ToLower(host)
+}
+
+// registryProfiles maps registry hosts in c_docker_registries to profiles, the first profile is used for a host
+func (c *Config) registryProfiles() map[string]string {
+	profiles := make(map[string]string)
+	for _, section := range c.Conf.Sections() {
+		var conf ConfigData
+		if err := section.MapTo(&conf); err != nil || conf.DockerRegistries == "" {
+			continue
+		}
+		for _, registry := range strings.Split(conf.DockerRegistries, ",") {
+			host := registryHost(registry)
+			if _, ok := profiles[host]; host != "" && !ok {
+				profiles[host] = awslogin.ShortSectionName(section.Name())
+			}
+		}
+	}
+	return profiles
+}
+
+// lockECRTokens locks cached ecr tokens for read-modify-write, docker runs helpers of parallel pulls at once.
+// The cache is best effort, it is used without lock if the lock could not be taken.
+func lockECRTokens() (unlock func()) {
+	unlock = func() {}
+	if dryRun {
+		return unlock
+	}
+	path := cacheFilePath(ecrTokensFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		debugf("failed to create cache folder, %v", err)
+		return unlock
+	}
+	locked, err := awslogin.LockFile(path + ".lock")
+	if err != nil {
+		debugf("failed to lock %s, %v", path, err)
+		return unlock
+	}
+	return locked
+}
+
+// ecrCredential gets docker login of registry with session of its profile, cached until it expires
+func ecrCredential(config *Config, serverURL string, now time.Time) (*dockerCredential, error) {
+	host := registryHost(serverURL)
+	profile, ok := config.registryProfiles()[host]
+	if !ok {
+		return nil, errDockerNotFound
+	}
+	m := ecrRegistryHost.FindStringSubmatch(host)
+	if m == nil {
+		return nil, fmt.Errorf("%s of profile %s is not an ecr registry", host, profile)
+	}
+
+	// parallel helpers wait for the token fetched by the first one
+	defer lockECRTokens()()
+	entries := make(map[string]ecrTokenEntry)
+	readCacheJSON(ecrTokensFile, &entries)
+	if entry, ok := entries[host]; ok && entry.Profile == profile && entry.ExpiresAt.Sub(now) > ecrTokenMargin {
+		debugf("using cached ecr token of %s, expires at %s", host, entry.ExpiresAt)
+		return &dockerCredential{ServerURL: serverURL, Username: entry.Username, Secret: entry.Password}, nil
+	}
+
+	// an expired session is reported as it is, instead of the error of ecr
+	if _, err := loadExportData(config, profile); err != nil {
+		return nil, err
+	}
+	client := config.clientConfigFor(profile)
+	client.Region = m[1]
+	auth, err := aws.GetECRAuthorizationToken(profile, client)
+	if err != nil {
+		return nil, awslogin.NewLoginError(profile, "failed to get ecr authorization token", err, false)
+	}
+	entries[host] = ecrTokenEntry{Profile: profile, Username: auth.Username, Password: auth.Password, ExpiresAt: auth.ExpiresAt}
+	writeCacheJSON(ecrTokensFile, entries)
+	return &dockerCredential{ServerURL: serverURL, Username: auth.Username, Secret: auth.Password}, nil
+}
+
+// runDockerCredential runs action of docker credential helper protocol, reading request from in and writing response to out.
+// Docker stores nothing in aws-login, store is accepted and ignored.
+func runDockerCredential(config *Config, action string, in io.Reader, out io.Writer) error {
+	switch action {
+	case "get":
+		serverURL, _ := bufio.NewReader(in).ReadString('\n')
+		cred, err := ecrCredential(config, strings.TrimSpace(serverURL), time.Now())
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(out).Encode(cred)
+	case "store":
+		_, err := ioutil.ReadAll(in)
+		return err
+	case "erase":
+		serverURL, _ := bufio.NewReader(in).ReadString('\n')
+		defer lockECRTokens()()
+		entries := make(map[string]ecrTokenEntry)
+		readCacheJSON(ecrTokensFile, &entries)
+		if _, ok := entries[registryHost(serverURL)]; ok {
+			delete(entries, registryHost(serverURL))
+			writeCacheJSON(ecrTokensFile, entries)
+		}
+		return nil
+	case "list":
+		registries := make(map[string]string)
+		for host := range config.registryProfiles() {
+			registries[host] = "AWS"
+		}
+		return json.NewEncoder(out).Encode(registries)
+	default:
+		return fmt.Errorf("unknown action %q, one of get, store, erase, list", action)
+	}
+}
+
+func dockerCredentialAction(c *cli.Context) error {
+	config, err := NewConfig(awsFoldPath)
+	if err == nil {
+		err = runDockerCredential(config, c.Args().First(), os.Stdin, os.Stdout)
+	}
+	if err != nil {
+		// docker reads the error from stdout
+		fmt.Println(err)
+		return cli.Exit("", 1)
+	}
+	return nil
+}
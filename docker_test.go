@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sixleaveakkm/aws-login/internal/awstest"
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDockerHelperArgs(t *testing.T) {
+	assert.Equal(t, []string{"/usr/local/bin/docker-credential-aws-login", "docker-credential", "get"}, dockerHelperArgs([]string{"/usr/local/bin/docker-credential-aws-login", "get"}))
+	assert.Equal(t, []string{"aws-login", "list"}, dockerHelperArgs([]string{"aws-login", "list"}))
+}
+
+func TestDockerCredential(t *testing.T) {
+	const registry = "123456789012.dkr.ecr.ap-northeast-1.amazonaws.com"
+	valid := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	dir := awstest.WriteFolder(t, `
+[profile dev]
+region = us-east-1
+mfa_serial = arn:aws:iam::123456789012:mfa/user
+c_docker_registries = `+registry+`, ghcr.io
+`, `
+[dev]
+aws_access_key_id = KEY
+aws_secret_access_key = SECRET
+aws_session_token = TOKEN
+aws_expiration = `+valid+`
+`)
+	defer os.RemoveAll(dir)
+	_ = os.Remove(cacheFilePath(ecrTokensFile))
+	defer os.Remove(cacheFilePath(ecrTokensFile))
+	defer os.Remove(cacheFilePath(ecrTokensFile) + ".lock")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := awslogin.NewMockAWS(ctrl)
+	aws = m
+	// the second get is answered from cache
+	m.EXPECT().GetECRAuthorizationToken("dev", gomock.Any()).DoAndReturn(func(profile string, client ClientConfig) (*awslogin.ECRAuthorization, error) {
+		assert.Equal(t, "ap-northeast-1", client.Region)
+		return &awslogin.ECRAuthorization{Username: "AWS", Password: "PASSWORD", ExpiresAt: time.Now().Add(12 * time.Hour)}, nil
+	})
+
+	config := mustNewConfig(t, dir)
+	for i := 0; i < 2; i++ {
+		var out bytes.Buffer
+		assert.NoError(t, runDockerCredential(config, "get", strings.NewReader("https://"+registry+"\n"), &out))
+		assert.Equal(t, `{"ServerURL":"https://`+registry+`","Username":"AWS","Secret":"PASSWORD"}`+"\n", out.String())
+	}
+
+	var out bytes.Buffer
+	assert.Equal(t, errDockerNotFound, runDockerCredential(config, "get", strings.NewReader("docker.io\n"), &out))
+	assert.Error(t, runDockerCredential(config, "get", strings.NewReader("ghcr.io\n"), &out))
+
+	assert.NoError(t, runDockerCredential(config, "list", nil, &out))
+	assert.Equal(t, `{"`+registry+`":"AWS","ghcr.io":"AWS"}`+"\n", out.String())
+
+	assert.NoError(t, runDockerCredential(config, "erase", strings.NewReader(registry), &out))
+	entries := make(map[string]ecrTokenEntry)
+	readCacheJSON(ecrTokensFile, &entries)
+	assert.Empty(t, entries)
+}
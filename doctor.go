@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/ini.v1"
+)
+
+const Fix = "fix"
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+var DoctorCommand = &cli.Command{
+	Name:   "doctor",
+	Usage:  "check aws config and credentials files for problems",
+	Action: doctorAction,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  Fix,
+			Usage: "fix problems which are safe to fix, files are backed up first",
+		},
+	},
+}
+
+// Finding is a problem found by doctor
+type Finding struct {
+	Severity string `json:"severity"`
+	Check    string `json:"check"`
+	Profile  string `json:"profile,omitempty"`
+	Message  string `json:"message"`
+	Fixable  bool   `json:"fixable"`
+	Fixed    bool   `json:"fixed"`
+
+	fix func(c *Config) error
+}
+
+// DoctorResult is printed after doctor
+type DoctorResult struct {
+	Findings     []*Finding `json:"findings"`
+	Backups      []string   `json:"backups,omitempty"`
+	FilesChanged []string   `json:"files_changed"`
+}
+
+var sectionHeader = regexp.MustCompile(`^\s*\[([^\]]+)\]`)
+
+// diagnose runs all checks on config loaded from folder
+func diagnose(c *Config, folder string) []*Finding {
+	findings := make([]*Finding, 0)
+	findings = append(findings, checkDuplicateSections(folder)...)
+	findings = append(findings, checkProfileDefinedTwice(c)...)
+	findings = append(findings, checkManagedProfiles(c)...)
+	findings = append(findings, checkKeysInConfig(c)...)
+	findings = append(findings, checkCredentialsPermission(folder)...)
+	return findings
+}
+
+// checkDuplicateSections finds sections appear more than once in a file, they are merged silently when loading.
+func checkDuplicateSections(folder string) []*Finding {
+	findings := make([]*Finding, 0)
+	for _, file := range []string{configFile_, credentialsFile_} {
+		f, err := os.Open(filepath.Join(folder, file))
+		if err != nil {
+			continue
+		}
+		count := make(map[string]int)
+		var order []string
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if m := sectionHeader.FindStringSubmatch(scanner.Text()); m != nil {
+				name := strings.TrimSpace(m[1])
+				if count[name] == 0 {
+					order = append(order, name)
+				}
+				count[name]++
+			}
+		}
+		_ = f.Close()
+		for _, name := range order {
+			if count[name] > 1 {
+				findings = append(findings, &Finding{
+					Severity: SeverityWarning,
+					Check:    "duplicate_section",
+					Profile:  awslogin.ShortSectionName(name),
+					Message:  fmt.Sprintf("section [%s] appears %d times in %s, keys are merged", name, count[name], file),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// checkProfileDefinedTwice finds profiles defined as both [x] and [profile x] in config
+func checkProfileDefinedTwice(c *Config) []*Finding {
+	findings := make([]*Finding, 0)
+	for _, section := range c.Conf.Sections() {
+		name := section.Name()
+		if strings.HasPrefix(name, Profile+" ") || name == ini.DefaultSection || name == "default" {
+			continue
+		}
+		if _, err := c.Conf.GetSection(Profile + " " + name); err == nil {
+			findings = append(findings, &Finding{
+				Severity: SeverityWarning,
+				Check:    "profile_defined_twice",
+				Profile:  name,
+				Message:  fmt.Sprintf("profile is defined as both [%s] and [profile %s] in config, only one is used", name, name),
+			})
+		}
+	}
+	return findings
+}
+
+// checkManagedProfiles finds mfa profiles without "_no_mfa" backup, and role profiles whose source doesn't exist
+func checkManagedProfiles(c *Config) []*Finding {
+	findings := make([]*Finding, 0)
+	for _, p := range c.listManagedProfiles() {
+		profile := p.Profile
+		switch p.Kind {
+		case MFA:
+			if hasNoMFACredential(c, profile) {
+				continue
+			}
+			finding := &Finding{
+				Severity: SeverityError,
+				Check:    "missing_no_mfa",
+				Profile:  profile,
+				Message:  fmt.Sprintf("long-term keys of mfa profile are not backed up as [%s%s] in credentials", profile, excludeConfigPostfix),
+			}
+			if cred, err := c.Cred.GetSection(profile); err == nil && !cred.HasKey("aws_session_token") && cred.HasKey("aws_access_key_id") {
+				finding.Fixable = true
+				finding.fix = func(c *Config) error {
+					return c.BackupNoMFACredential(profile)
+				}
+			}
+			findings = append(findings, finding)
+		case Role:
+			if p.SourceProfile == "" {
+				if !awslogin.IsCredentialSource(p.CredentialSource) {
+					findings = append(findings, &Finding{
+						Severity: SeverityError,
+						Check:    "invalid_credential_source",
+						Profile:  profile,
+						Message:  fmt.Sprintf("credential source %q of role profile is not one of %s", p.CredentialSource, strings.Join(awslogin.CredentialSources, ", ")),
+					})
+				}
+				continue
+			}
+			if _, err := c.LoadSection(p.SourceProfile, c.Cred); err == nil {
+				continue
+			}
+			findings = append(findings, &Finding{
+				Severity: SeverityError,
+				Check:    "missing_source_profile",
+				Profile:  profile,
+				Message:  fmt.Sprintf("source profile %q of role profile is not found in credentials", p.SourceProfile),
+			})
+		}
+	}
+	return findings
+}
+
+// hasNoMFACredential checks "<profile>_no_mfa" or "profile <profile>_no_mfa" exists in credentials
+func hasNoMFACredential(c *Config, profile string) bool {
+	for _, name := range []string{profile + excludeConfigPostfix, Profile + " " + profile + excludeConfigPostfix} {
+		if _, err := c.Cred.GetSection(name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// checkKeysInConfig finds keys stored in config instead of credentials.
+// Moving keys is safe only when credentials has no keys for the profile.
+func checkKeysInConfig(c *Config) []*Finding {
+	findings := make([]*Finding, 0)
+	for _, section := range c.Conf.Sections() {
+		if !section.HasKey("aws_access_key_id") && !section.HasKey("aws_secret_access_key") {
+			continue
+		}
+		sectionName := section.Name()
+		profile := awslogin.ShortSectionName(sectionName)
+		finding := &Finding{
+			Severity: SeverityWarning,
+			Check:    "keys_in_config",
+			Profile:  profile,
+			Message:  fmt.Sprintf("keys are stored in [%s] of config file instead of credentials file", sectionName),
+		}
+		if cred, err := c.Cred.GetSection(profile); err != nil || !cred.HasKey("aws_access_key_id") {
+			finding.Fixable = true
+			finding.fix = func(c *Config) error {
+				from := c.Conf.Section(sectionName)
+				to := c.Cred.Section(profile)
+				for _, key := range awslogin.CredentialKeys {
+					if from.HasKey(key) {
+						to.Key(key).SetValue(from.Key(key).String())
+						from.DeleteKey(key)
+					}
+				}
+				if err := c.Save(c.Cred, credentialsFile_); err != nil {
+					return err
+				}
+				return c.Save(c.Conf, configFile_)
+			}
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// checkCredentialsPermission finds credentials file readable or writable by others
+func checkCredentialsPermission(folder string) []*Finding {
+	path := filepath.Join(folder, credentialsFile_)
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm()&0077 == 0 {
+		return nil
+	}
+	return []*Finding{{
+		Severity: SeverityError,
+		Check:    "credentials_permission",
+		Message:  fmt.Sprintf("%s has permission %04o, it should be 0600", path, info.Mode().Perm()),
+		Fixable:  true,
+		fix: func(_ *Config) error {
+			if dryRun {
+				return nil
+			}
+			return os.Chmod(path, 0600)
+		},
+	}}
+}
+
+// backupFiles copies config and credentials to "<file>.<timestamp>.bak" with permission 0600
+func backupFiles(folder string) ([]string, error) {
+	suffix := time.Now().Format("20060102150405")
+	backups := make([]string, 0, 2)
+	for _, file := range []string{configFile_, credentialsFile_} {
+		path := filepath.Join(folder, file)
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return backups, err
+		}
+		backup := fmt.Sprintf("%s.%s.bak", path, suffix)
+		if err := ioutil.WriteFile(backup, content, 0600); err != nil {
+			return backups, err
+		}
+		backups = append(backups, backup)
+	}
+	return backups, nil
+}
+
+func doctorAction(c *cli.Context) error {
+	config, err := NewConfig(awsFoldPath)
+	if err != nil {
+		return err
+	}
+	findings := diagnose(config, awsFoldPath)
+	result := &DoctorResult{Findings: findings}
+
+	if c.Bool(Fix) && hasFixable(findings) {
+		if !dryRun {
+			backups, err := backupFiles(awsFoldPath)
+			if err != nil {
+				return fmt.Errorf("failed to backup files, not fixing, %w", err)
+			}
+			result.Backups = backups
+		}
+		for _, f := range findings {
+			if !f.Fixable {
+				continue
+			}
+			if err := f.fix(config); err != nil {
+				return fmt.Errorf("failed to fix %s, %w", f.Check, err)
+			}
+			f.Fixed = true
+		}
+	}
+	result.FilesChanged = config.ChangedFiles()
+
+	if outputFormat == OutputJSON {
+		printJSON(result)
+	} else {
+		printFindings(result)
+	}
+
+	remaining := 0
+	for _, f := range findings {
+		if f.Severity == SeverityError && !f.Fixed {
+			remaining++
+		}
+	}
+	if remaining > 0 {
+		return fmt.Errorf("doctor found %d error(s)", remaining)
+	}
+	return nil
+}
+
+func hasFixable(findings []*Finding) bool {
+	for _, f := range findings {
+		if f.Fixable {
+			return true
+		}
+	}
+	return false
+}
+
+func printFindings(result *DoctorResult) {
+	if len(result.Findings) == 0 {
+		fmt.Println(a.Green("no problem found"))
+		return
+	}
+	for _, f := range result.Findings {
+		severity := a.Yellow(f.Severity)
+		if f.Severity == SeverityError {
+			severity = a.Red(f.Severity)
+		}
+		status := ""
+		if f.Fixed {
+			status = a.Green(" (fixed)").String()
+		} else if f.Fixable {
+			status = " (fixable with --fix)"
+		}
+		profile := ""
+		if f.Profile != "" {
+			profile = fmt.Sprintf("[%s] ", f.Profile)
+		}
+		fmt.Printf("%s %s: %s%s%s\n", a.Bold(severity), f.Check, profile, f.Message, status)
+	}
+	for _, backup := range result.Backups {
+		fmt.Printf("backup saved to %s\n", backup)
+	}
+}
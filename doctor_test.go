@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sixleaveakkm/aws-login/internal/awstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnose(t *testing.T) {
+	dir := awstest.WriteFolder(t, `
+[dev]
+region = us-east-1
+
+[profile dev]
+region = us-east-1
+mfa_serial = arn:aws:iam::123456789012:mfa/user
+
+[profile admin]
+c_source_profile = nowhere
+c_role_arn = arn:aws:iam::210987654321:role/admin
+
+[profile static]
+aws_access_key_id = STATIC_KEY
+aws_secret_access_key = STATIC_SECRET
+`, `
+[dev]
+aws_access_key_id = KEY
+aws_secret_access_key = SECRET
+
+[dev]
+region = us-east-1
+`)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, os.Chmod(filepath.Join(dir, credentialsFile_), 0644))
+
+	config := mustNewConfig(t, dir)
+	findings := diagnose(config, dir)
+	checks := make(map[string]*Finding)
+	for _, f := range findings {
+		checks[f.Check] = f
+	}
+	assert.Len(t, findings, 6)
+	assert.Equal(t, "dev", checks["duplicate_section"].Profile)
+	assert.Equal(t, "dev", checks["profile_defined_twice"].Profile)
+	assert.True(t, checks["missing_no_mfa"].Fixable)
+	assert.False(t, checks["missing_source_profile"].Fixable)
+	assert.Equal(t, "static", checks["keys_in_config"].Profile)
+	assert.True(t, checks["credentials_permission"].Fixable)
+
+	dryRun, config.DryRun = true, true
+	defer func() { dryRun, dryRunConfigs = false, nil }()
+	assert.NoError(t, checks["missing_no_mfa"].fix(config))
+	assert.NoError(t, checks["keys_in_config"].fix(config))
+	assert.Equal(t, "KEY", config.Cred.Section("dev_no_mfa").Key("aws_access_key_id").String())
+	assert.Equal(t, "STATIC_KEY", config.Cred.Section("static").Key("aws_access_key_id").String())
+	assert.False(t, config.Conf.Section("profile static").HasKey("aws_access_key_id"))
+
+	dryRun, config.DryRun = false, false
+	assert.NoError(t, checks["credentials_permission"].fix(config))
+	info, _ := os.Stat(filepath.Join(dir, credentialsFile_))
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	backups, err := backupFiles(dir)
+	assert.NoError(t, err)
+	assert.Len(t, backups, 2)
+}
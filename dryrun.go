@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/ini.v1"
+)
+
+const DryRun = "dry-run"
+
+var (
+	// dryRun keeps changes in memory, diffs are printed after command instead of saving files
+	dryRun bool
+	// dryRunConfigs are configs loaded in dry run mode, to print their diffs
+	dryRunConfigs []*Config
+	// dryRunFileDiffs are diffs of files other than config and credentials, e.g. kubeconfig
+	dryRunFileDiffs []string
+	// dryRunSkipped are actions with side effects outside aws folder which are not run, e.g. hooks
+	dryRunSkipped []string
+)
+
+var dryRunFlag = &cli.BoolFlag{
+	Name:  DryRun,
+	Usage: "do not save config and credentials files, print diff of pending changes instead",
+}
+
+// pendingDiff returns redacted unified diff of files changed in memory
+func (c *Config) pendingDiff() string {
+	var diff string
+	for _, file := range []struct {
+		name string
+		f    *ini.File
+	}{
+		{configFile_, c.Conf},
+		{credentialsFile_, c.Cred},
+	} {
+		if !c.Changed(file.name) {
+			continue
+		}
+		path := c.PathOf(file.name)
+		diff += unifiedDiff(path, path+" (dry run)", c.Original(file.name), awslogin.Render(file.f))
+	}
+	return redact(diff)
+}
+
+// printDryRunDiffs prints pending changes of all configs loaded in dry run mode
+func printDryRunDiffs(_ *cli.Context) error {
+	if !dryRun {
+		return nil
+	}
+	changed := false
+	for _, c := range dryRunConfigs {
+		if diff := c.pendingDiff(); diff != "" {
+			changed = true
+			fmt.Fprint(infoOut(), diff)
+		}
+	}
+	for _, diff := range dryRunFileDiffs {
+		if diff != "" {
+			changed = true
+			fmt.Fprint(infoOut(), diff)
+		}
+	}
+	for _, action := range dryRunSkipped {
+		changed = true
+		fmt.Fprintf(infoOut(), "dry run: skipped %s\n", action)
+	}
+	if !changed {
+		fmt.Fprintln(infoOut(), "dry run: no changes")
+	}
+	dryRunConfigs, dryRunFileDiffs, dryRunSkipped = nil, nil, nil
+	return nil
+}
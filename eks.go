@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	Cluster    = "cluster"
+	Region     = "region"
+	KubeConfig = "kubeconfig"
+	Context    = "context"
+
+	// execCredentialAPIVersion is the version of ExecCredential printed, also set in kubeconfig written
+	execCredentialAPIVersion = "client.authentication.k8s.io/v1beta1"
+)
+
+var eksFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:     Cluster,
+		Usage:    "name of eks cluster",
+		Required: true,
+	},
+	&cli.StringFlag{
+		Name:    Profile,
+		Aliases: []string{"p"},
+		Usage:   "profile authenticating to cluster",
+	},
+	&cli.StringFlag{
+		Name:  Region,
+		Usage: "region of cluster, region of profile if not given",
+	},
+}
+
+var EKSCommand = &cli.Command{
+	Name:  "eks",
+	Usage: "authenticate kubectl to eks clusters with sessions of profiles",
+	Subcommands: []*cli.Command{
+		{
+			Name:   "token",
+			Usage:  "print ExecCredential with eks token signed by session of profile, used by kubectl",
+			Action: eksTokenAction,
+			Flags:  eksFlags,
+		},
+		{
+			Name:   "kubeconfig",
+			Usage:  "write cluster, user and context to kubeconfig, the user runs `aws-login eks token`",
+			Action: eksKubeconfigAction,
+			Flags: append([]cli.Flag{
+				&cli.StringFlag{
+					Name:    KubeConfig,
+					Usage:   "kubeconfig file to write, the first file of KUBECONFIG env or ~/.kube/config if not given",
+					EnvVars: []string{"KUBECONFIG"},
+				},
+				&cli.StringFlag{
+					Name:  Context,
+					Usage: "name of context written, <profile>@<cluster> if not given",
+				},
+			}, eksFlags...),
+		},
+	},
+}
+
+// ExecCredential is what kubectl reads from exec credential plugins
+type ExecCredential struct {
+	Kind       string               `json:"kind"`
+	APIVersion string               `json:"apiVersion"`
+	Spec       struct{}             `json:"spec"`
+	Status     ExecCredentialStatus `json:"status"`
+}
+
+type ExecCredentialStatus struct {
+	ExpirationTimestamp string `json:"expirationTimestamp"`
+	Token               string `json:"token"`
+}
+
+// KubeconfigResult is printed after kubeconfig is written
+type KubeconfigResult struct {
+	Profile string `json:"profile"`
+	Cluster string `json:"cluster"`
+	Context string `json:"context"`
+	File    string `json:"file"`
+}
+
+// kubeConfig is a kubeconfig file, fields aws-login doesn't touch are kept as they are
+type kubeConfig struct {
+	APIVersion     string                 `yaml:"apiVersion"`
+	Kind           string                 `yaml:"kind"`
+	Clusters       []kubeNamed            `yaml:"clusters"`
+	Contexts       []kubeNamed            `yaml:"contexts"`
+	CurrentContext string                 `yaml:"current-context"`
+	Users          []kubeNamed            `yaml:"users"`
+	Rest           map[string]interface{} `yaml:",inline"`
+}
+
+// kubeNamed is a named cluster, context or user of kubeconfig
+type kubeNamed struct {
+	Name string                 `yaml:"name"`
+	Rest map[string]interface{} `yaml:",inline"`
+}
+
+// setNamed sets values under key of entry of name, entry is added if not found.
+// Other values of the entry, e.g. namespace of a context, are kept.
+func setNamed(entries []kubeNamed, name string, key string, values map[string]interface{}) []kubeNamed {
+	for i := range entries {
+		if entries[i].Name != name {
+			continue
+		}
+		if entries[i].Rest == nil {
+			entries[i].Rest = make(map[string]interface{})
+		}
+		// yaml.v2 reads nested maps with interface{} keys
+		merged, ok := entries[i].Rest[key].(map[interface{}]interface{})
+		if !ok {
+			merged = make(map[interface{}]interface{})
+		}
+		for k, v := range values {
+			merged[k] = v
+		}
+		entries[i].Rest[key] = merged
+		return entries
+	}
+	return append(entries, kubeNamed{Name: name, Rest: map[string]interface{}{key: values}})
+}
+
+// writeExecCredential writes token in the format of exec credential plugins
+func writeExecCredential(w io.Writer, token *awslogin.EKSToken) {
+	cred := ExecCredential{
+		Kind:       "ExecCredential",
+		APIVersion: execCredentialAPIVersion,
+		Status: ExecCredentialStatus{
+			ExpirationTimestamp: token.Expiration.UTC().Format(time.RFC3339),
+			Token:               token.Token,
+		},
+	}
+	content, _ := json.Marshal(&cred)
+	_, _ = fmt.Fprintln(w, string(content))
+}
+
+// setEKSContext adds cluster, and user and context of profile to kubeconfig.
+// The user runs aws-login by absolute path, kubectl may not run with the same PATH.
+// The context is used as current context.
+func (k *kubeConfig) setEKSContext(cluster *awslogin.EKSCluster, profile string, region string, context string) {
+	if k.APIVersion == "" {
+		k.APIVersion = "v1"
+	}
+	if k.Kind == "" {
+		k.Kind = "Config"
+	}
+	args := []string{"eks", "token", "--cluster", cluster.Name, "--profile", profile}
+	if region != "" {
+		args = append(args, "--region", region)
+	}
+	userName := fmt.Sprintf("aws-login:%s@%s", profile, cluster.Arn)
+
+	k.Clusters = setNamed(k.Clusters, cluster.Arn, "cluster", map[string]interface{}{
+		"server":                     cluster.Endpoint,
+		"certificate-authority-data": cluster.CertificateAuthority,
+	})
+	k.Users = setNamed(k.Users, userName, "user", map[string]interface{}{
+		"exec": map[string]interface{}{
+			"apiVersion": execCredentialAPIVersion,
+			"command":    awslogin.Executable(),
+			"args":       args,
+		},
+	})
+	k.Contexts = setNamed(k.Contexts, context, "context", map[string]interface{}{
+		"cluster": cluster.Arn,
+		"user":    userName,
+	})
+	k.CurrentContext = context
+}
+
+// kubeconfigPath gets the file to write, the first one if value is a list like KUBECONFIG
+func kubeconfigPath(value string) string {
+	for _, path := range filepath.SplitList(value) {
+		if path != "" {
+			return path
+		}
+	}
+	usr, _ := user.Current()
+	return filepath.Join(usr.HomeDir, ".kube", "config")
+}
+
+// eksClientConfig gets client settings of profile, with region of cluster if given
+func eksClientConfig(c *cli.Context, config *Config, profile string) ClientConfig {
+	client := config.clientConfigFor(profile)
+	if region := c.String(Region); region != "" {
+		client.Region = region
+	}
+	return client
+}
+
+func eksTokenAction(c *cli.Context) error {
+	profile := c.String(Profile)
+	if profile == "" {
+		profile = getProfile(c)
+	}
+	config, err := NewConfig(awsFoldPath)
+	if err != nil {
+		return err
+	}
+	data, err := loadExportData(config, profile)
+	if err != nil {
+		return err
+	}
+	token, err := awslogin.NewEKSToken(data.Credential, c.String(Cluster), eksClientConfig(c, config, profile))
+	if err != nil {
+		return err
+	}
+	writeExecCredential(os.Stdout, token)
+	return nil
+}
+
+func eksKubeconfigAction(c *cli.Context) error {
+	profile := c.String(Profile)
+	if profile == "" {
+		profile = getProfile(c)
+	}
+	config, err := NewConfig(awsFoldPath)
+	if err != nil {
+		return err
+	}
+	if _, err := config.ProfileConfig(profile); err != nil {
+		return &LoginError{Kind: awslogin.ProfileNotFound, Profile: profile, Err: err}
+	}
+	client := eksClientConfig(c, config, profile)
+	cluster, err := aws.DescribeEKSCluster(profile, client, c.String(Cluster))
+	if err != nil {
+		return fmt.Errorf("failed to describe cluster %s, %w", c.String(Cluster), err)
+	}
+
+	path := kubeconfigPath(c.String(KubeConfig))
+	original, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var kube kubeConfig
+	if err := yaml.Unmarshal(original, &kube); err != nil {
+		return fmt.Errorf("failed to read %s, %w", path, err)
+	}
+	context := c.String(Context)
+	if context == "" {
+		context = profile + "@" + cluster.Name
+	}
+	// region is written so the token is signed in region of cluster, even if region of profile changes
+	kube.setEKSContext(cluster, profile, client.Region, context)
+	content, err := yaml.Marshal(&kube)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		dryRunFileDiffs = append(dryRunFileDiffs, unifiedDiff(path, path+" (dry run)", string(original), string(content)))
+	} else {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return err
+		}
+		if err := writeSecretFile(path, content); err != nil {
+			return err
+		}
+	}
+
+	result := &KubeconfigResult{Profile: profile, Cluster: cluster.Arn, Context: context, File: path}
+	if outputFormat == OutputJSON {
+		printJSON(result)
+		return nil
+	}
+	if !dryRun {
+		fmt.Fprintf(infoOut(), "context %s of %s is written to %s\n", context, cluster.Arn, path)
+	}
+	return nil
+}
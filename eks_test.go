@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestWriteExecCredential(t *testing.T) {
+	var buf bytes.Buffer
+	writeExecCredential(&buf, &awslogin.EKSToken{Token: "k8s-aws-v1.abc", Expiration: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)})
+	assert.Equal(t, `{"kind":"ExecCredential","apiVersion":"client.authentication.k8s.io/v1beta1","spec":{},"status":{"expirationTimestamp":"2030-01-01T00:00:00Z","token":"k8s-aws-v1.abc"}}`+"\n", buf.String())
+}
+
+func TestSetEKSContext(t *testing.T) {
+	original := `apiVersion: v1
+kind: Config
+clusters:
+- name: minikube
+  cluster:
+    server: https://127.0.0.1:8443
+contexts:
+- name: dev@prod
+  context:
+    cluster: old
+    namespace: x
+    user: old
+current-context: minikube
+users:
+- name: minikube
+  user:
+    client-certificate: /home/user/.minikube/client.crt
+preferences: {}
+`
+	var kube kubeConfig
+	assert.NoError(t, yaml.Unmarshal([]byte(original), &kube))
+	cluster := &awslogin.EKSCluster{
+		Name:                 "prod",
+		Arn:                  "arn:aws:eks:ap-northeast-1:123456789012:cluster/prod",
+		Endpoint:             "https://ABC.gr7.ap-northeast-1.eks.amazonaws.com",
+		CertificateAuthority: "Q0E=",
+	}
+	kube.setEKSContext(cluster, "dev", "ap-northeast-1", "dev@prod")
+	kube.setEKSContext(cluster, "dev", "ap-northeast-1", "dev@prod")
+	content, err := yaml.Marshal(&kube)
+	assert.NoError(t, err)
+
+	var saved kubeConfig
+	assert.NoError(t, yaml.Unmarshal(content, &saved))
+	assert.Equal(t, "dev@prod", saved.CurrentContext)
+	assert.Len(t, saved.Clusters, 2)
+	assert.Len(t, saved.Users, 2)
+	assert.Len(t, saved.Contexts, 1)
+	assert.Contains(t, saved.Rest, "preferences")
+	assert.Contains(t, string(content), "client-certificate: /home/user/.minikube/client.crt")
+	assert.Contains(t, string(content), `      args:
+      - eks
+      - token
+      - --cluster
+      - prod
+      - --profile
+      - dev
+      - --region
+      - ap-northeast-1
+      command: `+awslogin.Executable()+`
+`)
+	assert.Equal(t, map[interface{}]interface{}{
+		"cluster":   "arn:aws:eks:ap-northeast-1:123456789012:cluster/prod",
+		"namespace": "x",
+		"user":      "aws-login:dev@arn:aws:eks:ap-northeast-1:123456789012:cluster/prod",
+	}, saved.Contexts[0].Rest["context"])
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+)
+
+// Exit codes of aws-login, 1 is used for errors not classified.
+const (
+	ExitUnknown           = 1
+	ExitProfileNotFound   = 3
+	ExitInvalidMFACode    = 4
+	ExitRoleAccessDenied  = 5
+	ExitInvalidCredential = 6
+	ExitNetworkFailure    = 7
+	ExitHookFailed        = 8
+	ExitReusedMFACode     = 9
+	ExitInvalidConfig     = 10
+)
+
+// kindExitCode is the process exit code for errors of kind.
+func kindExitCode(k ErrorKind) int {
+	switch k {
+	case awslogin.ProfileNotFound:
+		return ExitProfileNotFound
+	case awslogin.InvalidMFACode:
+		return ExitInvalidMFACode
+	case awslogin.RoleAccessDenied:
+		return ExitRoleAccessDenied
+	case awslogin.InvalidCredential:
+		return ExitInvalidCredential
+	case awslogin.NetworkFailure:
+		return ExitNetworkFailure
+	case awslogin.HookFailed:
+		return ExitHookFailed
+	case awslogin.ReusedMFACode:
+		return ExitReusedMFACode
+	case awslogin.InvalidConfig:
+		return ExitInvalidConfig
+	default:
+		return ExitUnknown
+	}
+}
+
+// exitCode gets exit code for error returned by actions.
+func exitCode(err error) int {
+	var loginErr *LoginError
+	if errors.As(err, &loginErr) {
+		return kindExitCode(loginErr.Kind)
+	}
+	if errors.Is(err, awslogin.ErrProfileNotFound) {
+		return ExitProfileNotFound
+	}
+	return ExitUnknown
+}
+
+// printError prints error with its hint to stderr.
+func printError(err error) {
+	fmt.Fprintln(os.Stderr, a.Bold(a.BrightRed(fmt.Sprintf("x %v", err))))
+	var loginErr *LoginError
+	if errors.As(err, &loginErr) && loginErr.Hint() != "" {
+		fmt.Fprintln(os.Stderr, a.BrightYellow(fmt.Sprintf("  hint: %s", loginErr.Hint())))
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_exitCode(t *testing.T) {
+	err := awslogin.NewLoginError("dummy", "failed get mfa",
+		awserr.New("AccessDenied", "MultiFactorAuthentication failed with invalid MFA one time pass code. ", nil), false)
+	assert.Equal(t, ExitInvalidMFACode, exitCode(err))
+	assert.Equal(t, ExitInvalidMFACode, exitCode(fmt.Errorf("wrapped, %w", err)))
+	assert.Equal(t, ExitReusedMFACode, exitCode(awslogin.NewLoginError("dummy", "failed get mfa",
+		awserr.New("AccessDenied", "MultiFactorAuthentication failed, the MFA one time pass code has already been used.", nil), false)))
+	assert.Equal(t, ExitProfileNotFound, exitCode(awslogin.ErrProfileNotFound))
+	assert.Equal(t, ExitUnknown, exitCode(errors.New("something")))
+
+	var loginErr *LoginError
+	assert.True(t, errors.As(&LoginError{Kind: awslogin.InvalidCredential, Profile: "admin", Source: "dev_no_mfa",
+		Err: errors.New("expired")}, &loginErr))
+	assert.Contains(t, loginErr.Hint(), "aws configure --profile dev_no_mfa")
+}
+
+func TestNewConfigError(t *testing.T) {
+	_, err := NewConfig(filepath.Join(os.TempDir(), "aws-login-missing-folder"))
+	assert.Error(t, err)
+	assert.Equal(t, ExitInvalidConfig, exitCode(err))
+	// json output renders it as other errors
+	result := newErrorResult(err)
+	assert.Equal(t, "invalid_config", result.Error.Kind)
+	assert.Equal(t, ExitInvalidConfig, result.Error.ExitCode)
+	assert.NotEmpty(t, result.Error.Hint)
+}
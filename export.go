@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	Format  = "format"
+	Out     = "out"
+	Refresh = "refresh"
+)
+
+var ExportCommand = &cli.Command{
+	Name:      "export",
+	Usage:     "export session credential of profile, e.g. for docker compose, terraform or ide",
+	ArgsUsage: "[code]",
+	Action:    exportAction,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    Profile,
+			Aliases: []string{"p"},
+			Usage:   "profile to export",
+		},
+		&cli.StringFlag{
+			Name:    Format,
+			Aliases: []string{"f"},
+			Usage:   "one of " + strings.Join(exportFormatNames(), ", "),
+			Value:   "dotenv",
+		},
+		&cli.StringFlag{
+			Name:  Out,
+			Usage: "write to file with permission 0600 instead of stdout",
+		},
+		&cli.BoolFlag{
+			Name:  Refresh,
+			Usage: "login again if session is expired, mfa code is given as argument or prompted",
+		},
+	},
+}
+
+// ExportData is what exported for a profile
+type ExportData struct {
+	Profile    string
+	Region     string
+	Credential *SessionCredential
+}
+
+// exportFormats writes ExportData in each format
+var exportFormats = map[string]func(w io.Writer, d *ExportData){
+	"dotenv":     writeDotenv,
+	"docker-env": writeDockerEnv,
+	"json":       writeExportJSON,
+	"tfvars":     writeTfvars,
+	"properties": writeProperties,
+}
+
+func exportFormatNames() []string {
+	names := make([]string, 0, len(exportFormats))
+	for name := range exportFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// envPairs are environment variables understood by aws sdks, empty values are skipped
+func (d *ExportData) envPairs() [][2]string {
+	pairs := [][2]string{
+		{"AWS_ACCESS_KEY_ID", d.Credential.AccessKey},
+		{"AWS_SECRET_ACCESS_KEY", d.Credential.SecretKey},
+		{"AWS_SESSION_TOKEN", d.Credential.SessionToken},
+		{"AWS_REGION", d.Region},
+		{"AWS_DEFAULT_REGION", d.Region},
+		{"AWS_CREDENTIAL_EXPIRATION", d.expiration()},
+	}
+	results := make([][2]string, 0, len(pairs))
+	for _, p := range pairs {
+		if p[1] != "" {
+			results = append(results, p)
+		}
+	}
+	return results
+}
+
+func (d *ExportData) expiration() string {
+	if d.Credential.Expiration.IsZero() {
+		return ""
+	}
+	return d.Credential.Expiration.UTC().Format(time.RFC3339)
+}
+
+func writeDotenv(w io.Writer, d *ExportData) {
+	fmt.Fprintf(w, "# aws-login profile %s\n", d.Profile)
+	for _, p := range d.envPairs() {
+		fmt.Fprintf(w, "%s=%q\n", p[0], p[1])
+	}
+}
+
+// writeDockerEnv writes `docker run --env-file` format, which takes values literally without quotes
+func writeDockerEnv(w io.Writer, d *ExportData) {
+	for _, p := range d.envPairs() {
+		fmt.Fprintf(w, "%s=%s\n", p[0], p[1])
+	}
+}
+
+func writeExportJSON(w io.Writer, d *ExportData) {
+	data := struct {
+		Profile         string `json:"Profile"`
+		AccessKeyId     string `json:"AccessKeyId"`
+		SecretAccessKey string `json:"SecretAccessKey"`
+		SessionToken    string `json:"SessionToken,omitempty"`
+		Region          string `json:"Region,omitempty"`
+		Expiration      string `json:"Expiration,omitempty"`
+	}{d.Profile, d.Credential.AccessKey, d.Credential.SecretKey, d.Credential.SessionToken, d.Region, d.expiration()}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(data)
+	_, _ = w.Write(buf.Bytes())
+}
+
+func writeTfvars(w io.Writer, d *ExportData) {
+	fmt.Fprintf(w, "# aws-login profile %s\n", d.Profile)
+	for _, p := range [][2]string{
+		{"aws_access_key", d.Credential.AccessKey},
+		{"aws_secret_key", d.Credential.SecretKey},
+		{"aws_session_token", d.Credential.SessionToken},
+		{"aws_region", d.Region},
+		{"aws_session_expiration", d.expiration()},
+	} {
+		if p[1] != "" {
+			fmt.Fprintf(w, "%s = %q\n", p[0], p[1])
+		}
+	}
+}
+
+// writeProperties writes java system properties read by aws sdk for java
+func writeProperties(w io.Writer, d *ExportData) {
+	fmt.Fprintf(w, "# aws-login profile %s\n", d.Profile)
+	for _, p := range [][2]string{
+		{"aws.accessKeyId", d.Credential.AccessKey},
+		{"aws.secretAccessKey", d.Credential.SecretKey},
+		{"aws.sessionToken", d.Credential.SessionToken},
+		{"aws.region", d.Region},
+		{"aws.credentialExpiration", d.expiration()},
+	} {
+		if p[1] != "" {
+			fmt.Fprintf(w, "%s=%s\n", p[0], p[1])
+		}
+	}
+}
+
+// loadExportData gets session credential and region of profile from files.
+// It fails if session is expired or not logged in.
+func loadExportData(config *Config, profile string) (*ExportData, error) {
+	cred, err := config.LoadSession(profile)
+	if errors.Is(err, awslogin.ErrProfileNotFound) {
+		return nil, &LoginError{Kind: awslogin.ProfileNotFound, Profile: profile, Err: err}
+	}
+	if err != nil {
+		// session of cache-only profile is not cached yet
+		cred = &SessionCredential{}
+	}
+	switch sessionState(cred) {
+	case StateExpired:
+		return nil, fmt.Errorf("session of %q expired at %s, login again or use --refresh", profile, cred.Expiration.Local().Format(time.RFC3339))
+	case StateNoSession:
+		return nil, fmt.Errorf("%q has no session credential, login first or use --refresh", profile)
+	}
+	data := &ExportData{Profile: profile, Credential: cred}
+	if conf, err := config.LoadConfig(profile); err == nil {
+		data.Region = conf.Region
+	}
+	return data, nil
+}
+
+// writeSecretFile writes content to path with permission 0600, also for existing file
+func writeSecretFile(path string, content []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := f.Chmod(0600); err != nil {
+		return err
+	}
+	_, err = f.Write(content)
+	return err
+}
+
+func exportAction(c *cli.Context) error {
+	profile := getProfile(c)
+	write, ok := exportFormats[c.String(Format)]
+	if !ok {
+		return fmt.Errorf("format must be one of %s, got %q", strings.Join(exportFormatNames(), ", "), c.String(Format))
+	}
+
+	config, err := NewConfig(awsFoldPath)
+	if err != nil {
+		return err
+	}
+	data, err := loadExportData(config, profile)
+	if err != nil {
+		if !c.Bool(Refresh) {
+			return err
+		}
+		code, err := getCode(profile, c.Args().Get(0))
+		if err != nil {
+			return err
+		}
+		if _, err := login(profile, code, false); err != nil {
+			return err
+		}
+		// login saved files, they are read again
+		if config, err = NewConfig(awsFoldPath); err != nil {
+			return err
+		}
+		if data, err = loadExportData(config, profile); err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	write(&buf, data)
+	if path := c.String(Out); path != "" {
+		// content is not diffed, it holds secrets
+		if dryRun {
+			dryRunSkipped = append(dryRunSkipped, fmt.Sprintf("export of %s to %s", profile, path))
+			return nil
+		}
+		if err := writeSecretFile(path, buf.Bytes()); err != nil {
+			return fmt.Errorf("failed to write %s, %w", path, err)
+		}
+		fmt.Fprintf(infoOut(), "exported %s to %s\n", profile, path)
+		return nil
+	}
+	_, err = os.Stdout.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sixleaveakkm/aws-login/internal/awstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportFormats(t *testing.T) {
+	data := &ExportData{
+		Profile: "dev",
+		Region:  "us-east-1",
+		Credential: &SessionCredential{
+			AccessKey:    "KEY",
+			SecretKey:    "SECRET",
+			SessionToken: "TOKEN",
+			Expiration:   time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"dotenv", `# aws-login profile dev
+AWS_ACCESS_KEY_ID="KEY"
+AWS_SECRET_ACCESS_KEY="SECRET"
+AWS_SESSION_TOKEN="TOKEN"
+AWS_REGION="us-east-1"
+AWS_DEFAULT_REGION="us-east-1"
+AWS_CREDENTIAL_EXPIRATION="2030-01-01T00:00:00Z"
+`},
+		{"docker-env", `AWS_ACCESS_KEY_ID=KEY
+AWS_SECRET_ACCESS_KEY=SECRET
+AWS_SESSION_TOKEN=TOKEN
+AWS_REGION=us-east-1
+AWS_DEFAULT_REGION=us-east-1
+AWS_CREDENTIAL_EXPIRATION=2030-01-01T00:00:00Z
+`},
+		{"json", `{
+  "Profile": "dev",
+  "AccessKeyId": "KEY",
+  "SecretAccessKey": "SECRET",
+  "SessionToken": "TOKEN",
+  "Region": "us-east-1",
+  "Expiration": "2030-01-01T00:00:00Z"
+}
+`},
+		{"tfvars", `# aws-login profile dev
+aws_access_key = "KEY"
+aws_secret_key = "SECRET"
+aws_session_token = "TOKEN"
+aws_region = "us-east-1"
+aws_session_expiration = "2030-01-01T00:00:00Z"
+`},
+		{"properties", `# aws-login profile dev
+aws.accessKeyId=KEY
+aws.secretAccessKey=SECRET
+aws.sessionToken=TOKEN
+aws.region=us-east-1
+aws.credentialExpiration=2030-01-01T00:00:00Z
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			exportFormats[tt.format](&buf, data)
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestLoadExportData(t *testing.T) {
+	valid := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	expired := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	dir := awstest.WriteFolder(t, `
+[profile dev]
+region = us-east-1
+`, `
+[dev]
+aws_access_key_id = KEY
+aws_secret_access_key = SECRET
+aws_session_token = TOKEN
+aws_expiration = `+valid+`
+
+[old]
+aws_access_key_id = KEY
+aws_secret_access_key = SECRET
+aws_session_token = TOKEN
+aws_expiration = `+expired+`
+
+[static]
+aws_access_key_id = KEY
+aws_secret_access_key = SECRET
+`)
+	defer os.RemoveAll(dir)
+	config := mustNewConfig(t, dir)
+
+	data, err := loadExportData(config, "dev")
+	assert.NoError(t, err)
+	assert.Equal(t, "us-east-1", data.Region)
+	assert.Equal(t, "TOKEN", data.Credential.SessionToken)
+
+	_, err = loadExportData(config, "old")
+	assert.Error(t, err)
+	_, err = loadExportData(config, "static")
+	assert.Error(t, err)
+	_, err = loadExportData(config, "missing")
+	assert.Equal(t, ExitProfileNotFound, exitCode(err))
+
+	path := filepath.Join(dir, "out.env")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("old"), 0644))
+	assert.NoError(t, writeSecretFile(path, []byte("new")))
+	info, _ := os.Stat(path)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	content, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "new", string(content))
+}
+
+func TestExportOutDryRun(t *testing.T) {
+	valid := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	dir := awstest.WriteFolder(t, "[profile dev]\nregion = us-east-1\n",
+		"[dev]\naws_access_key_id = KEY\naws_secret_access_key = SECRET\naws_session_token = TOKEN\naws_expiration = "+valid+"\n")
+	defer os.RemoveAll(dir)
+	originalFolder := awsFoldPath
+	awsFoldPath = dir
+	defer func() { awsFoldPath = originalFolder }()
+	defer func() { dryRun = false }()
+
+	path := filepath.Join(dir, "out.env")
+	out := captureStdout(t, func() {
+		executor([]string{"aws-login", "--dry-run", "export", "-p", "dev", "--out", path})
+	})
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	assert.Contains(t, out, "dry run: skipped export of dev to "+path)
+	assert.NotContains(t, out, "SECRET")
+}
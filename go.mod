@@ -12,5 +12,6 @@ require (
 	github.com/urfave/cli/v2 v2.27.2
 	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
 	gopkg.in/ini.v1 v1.67.0
-	gopkg.in/yaml.v2 v2.4.0 // indirect
+	gopkg.in/yaml.v2 v2.4.0
+	rsc.io/qr v0.2.0
 )
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	PreLoginHook  = "pre-login-hook"
+	PostLoginHook = "post-login-hook"
+	HookTimeout   = "hook-timeout"
+	HookSecrets   = "hook-secrets"
+	HookStrict    = "hook-strict"
+
+	HookStagePre  = "pre-login"
+	HookStagePost = "post-login"
+
+	// DefaultHookTimeout is used when neither flag nor profile sets it
+	DefaultHookTimeout = 30 * time.Second
+)
+
+// HookConfig holds executables run before and after login.
+//
+// A failed or timed out pre-login hook fails the login, nothing is saved.
+// A failed post-login hook only prints a warning, as the session is already saved,
+// unless Strict is set, then the login fails with ExitHookFailed.
+type HookConfig struct {
+	Pre  []string
+	Post []string
+
+	Timeout time.Duration
+	// Secrets passes session credential to post-login hooks
+	Secrets bool
+	Strict  bool
+}
+
+var globalHooks HookConfig
+
+var hookFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:    PreLoginHook,
+		Usage:   "executable run before every login, runs before c_pre_login_hook of the profile",
+		EnvVars: []string{"AWS_LOGIN_PRE_LOGIN_HOOK"},
+	},
+	&cli.StringFlag{
+		Name:    PostLoginHook,
+		Usage:   "executable run after every login, runs before c_post_login_hook of the profile",
+		EnvVars: []string{"AWS_LOGIN_POST_LOGIN_HOOK"},
+	},
+	&cli.DurationFlag{
+		Name:    HookTimeout,
+		Usage:   "timeout of each hook, overridden by c_hook_timeout (seconds) of the profile",
+		Value:   DefaultHookTimeout,
+		EnvVars: []string{"AWS_LOGIN_HOOK_TIMEOUT"},
+	},
+	&cli.BoolFlag{
+		Name:    HookSecrets,
+		Usage:   "pass session credential to post-login hooks, also enabled by c_hook_secrets of the profile",
+		EnvVars: []string{"AWS_LOGIN_HOOK_SECRETS"},
+	},
+	&cli.BoolFlag{
+		Name:    HookStrict,
+		Usage:   "fail login when post-login hook fails, also enabled by c_hook_strict of the profile",
+		EnvVars: []string{"AWS_LOGIN_HOOK_STRICT"},
+	},
+}
+
+// loadGlobalHookConfig reads global hooks from flags
+func loadGlobalHookConfig(c *cli.Context) {
+	globalHooks = HookConfig{
+		Timeout: c.Duration(HookTimeout),
+		Secrets: c.Bool(HookSecrets),
+		Strict:  c.Bool(HookStrict),
+	}
+	if hook := c.String(PreLoginHook); hook != "" {
+		globalHooks.Pre = []string{hook}
+	}
+	if hook := c.String(PostLoginHook); hook != "" {
+		globalHooks.Post = []string{hook}
+	}
+}
+
+// withProfile returns hooks with ones of profile appended after global ones
+func (h HookConfig) withProfile(conf *ConfigData) HookConfig {
+	merged := HookConfig{
+		Pre:     append([]string{}, h.Pre...),
+		Post:    append([]string{}, h.Post...),
+		Timeout: h.Timeout,
+		Secrets: h.Secrets || conf.HookSecrets,
+		Strict:  h.Strict || conf.HookStrict,
+	}
+	if conf.PreLoginHook != "" {
+		merged.Pre = append(merged.Pre, conf.PreLoginHook)
+	}
+	if conf.PostLoginHook != "" {
+		merged.Post = append(merged.Post, conf.PostLoginHook)
+	}
+	if conf.HookTimeout > 0 {
+		merged.Timeout = time.Duration(conf.HookTimeout) * time.Second
+	}
+	if merged.Timeout <= 0 {
+		merged.Timeout = DefaultHookTimeout
+	}
+	return merged
+}
+
+// hookEnv makes environments passed to hooks.
+// cred is nil for pre-login hooks, secrets are passed only if opted in.
+func (h HookConfig) hookEnv(stage string, profile string, kind string, conf *ConfigData, cred *SessionCredential) []string {
+	identity := conf.SerialNumber
+	if kind == Role {
+		identity = conf.AssumeRoleArn
+	}
+	env := []string{
+		"AWS_LOGIN_HOOK=" + stage,
+		"AWS_LOGIN_PROFILE=" + profile,
+		"AWS_LOGIN_KIND=" + kind,
+		"AWS_LOGIN_ACCOUNT_ID=" + awslogin.AccountIDFromArn(identity),
+		"AWS_REGION=" + conf.Region,
+	}
+	if cred == nil {
+		return env
+	}
+	env = append(env, "AWS_PROFILE="+profile)
+	if !cred.Expiration.IsZero() {
+		env = append(env, "AWS_LOGIN_EXPIRATION="+cred.Expiration.UTC().Format(time.RFC3339))
+	}
+	if h.Secrets {
+		env = append(env,
+			"AWS_ACCESS_KEY_ID="+cred.AccessKey,
+			"AWS_SECRET_ACCESS_KEY="+cred.SecretKey,
+			"AWS_SESSION_TOKEN="+cred.SessionToken,
+		)
+	}
+	return env
+}
+
+// runHooks runs hooks one by one, stops at the first failure. In dry run hooks are only reported
+func (h HookConfig) runHooks(stage string, hooks []string, env []string) error {
+	for _, hook := range hooks {
+		if dryRun {
+			dryRunSkipped = append(dryRunSkipped, fmt.Sprintf("%s hook %q", stage, hook))
+			continue
+		}
+		if err := h.runHook(stage, hook, env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runHook runs executable with its arguments split by spaces, with timeout.
+// Output of hook goes to stderr, keeping stdout for results.
+func (h HookConfig) runHook(stage string, hook string, env []string) error {
+	args := strings.Fields(hook)
+	if len(args) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
+	defer cancel()
+
+	debugf("running %s hook %q with timeout %s", stage, hook, h.Timeout)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%s hook %q timed out after %s", stage, hook, h.Timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%s hook %q failed, %w", stage, hook, err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeHook writes an executable shell script to dir
+func writeHook(t *testing.T, dir string, name string, script string) string {
+	path := filepath.Join(dir, name)
+	assert.NoError(t, ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script), 0700))
+	return path
+}
+
+func TestHookConfigWithProfile(t *testing.T) {
+	global := HookConfig{Pre: []string{"global-pre"}, Timeout: time.Minute}
+	merged := global.withProfile(&ConfigData{PreLoginHook: "pre", PostLoginHook: "post", HookTimeout: 5, HookSecrets: true})
+	assert.Equal(t, []string{"global-pre", "pre"}, merged.Pre)
+	assert.Equal(t, []string{"post"}, merged.Post)
+	assert.Equal(t, 5*time.Second, merged.Timeout)
+	assert.True(t, merged.Secrets)
+	assert.Equal(t, []string{"global-pre"}, global.Pre)
+
+	assert.Equal(t, DefaultHookTimeout, HookConfig{}.withProfile(&ConfigData{}).Timeout)
+}
+
+func TestHookEnv(t *testing.T) {
+	conf := &ConfigData{Region: "us-east-1", SourceProfile: "dev", AssumeRoleArn: "arn:aws:iam::210987654321:role/admin"}
+	cred := &SessionCredential{AccessKey: "KEY", SecretKey: "SECRET", SessionToken: "TOKEN", Expiration: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	env := strings.Join(HookConfig{}.hookEnv(HookStagePost, "admin", Role, conf, cred), "\n")
+	assert.Contains(t, env, "AWS_LOGIN_PROFILE=admin")
+	assert.Contains(t, env, "AWS_LOGIN_ACCOUNT_ID=210987654321")
+	assert.Contains(t, env, "AWS_REGION=us-east-1")
+	assert.Contains(t, env, "AWS_LOGIN_EXPIRATION=2030-01-01T00:00:00Z")
+	assert.NotContains(t, env, "SECRET")
+	assert.NotContains(t, env, "TOKEN")
+
+	env = strings.Join(HookConfig{Secrets: true}.hookEnv(HookStagePost, "admin", Role, conf, cred), "\n")
+	assert.Contains(t, env, "AWS_SECRET_ACCESS_KEY=SECRET")
+	assert.Contains(t, env, "AWS_SESSION_TOKEN=TOKEN")
+}
+
+func TestRunHook(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "aws-login-hook")
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+	ok := writeHook(t, dir, "ok", `echo "$AWS_LOGIN_HOOK $AWS_LOGIN_PROFILE $1" > `+out+"\n")
+	fail := writeHook(t, dir, "fail", "exit 3\n")
+	slow := writeHook(t, dir, "slow", "exec sleep 5\n")
+
+	h := HookConfig{Timeout: time.Second}
+	assert.NoError(t, h.runHook(HookStagePre, ok+" arg", []string{"AWS_LOGIN_HOOK=pre-login", "AWS_LOGIN_PROFILE=dev"}))
+	content, _ := ioutil.ReadFile(out)
+	assert.Equal(t, "pre-login dev arg\n", string(content))
+
+	assert.Error(t, h.runHook(HookStagePre, fail, nil))
+	start := time.Now()
+	err := HookConfig{Timeout: 100 * time.Millisecond}.runHook(HookStagePre, slow, nil)
+	assert.Contains(t, err.Error(), "timed out")
+	assert.True(t, time.Since(start) < 3*time.Second)
+}
+
+func TestLoginPreHookFailure(t *testing.T) {
+	originalFolder := awsFoldPath
+	awsFoldPath = mfaFolder
+	defer func() { awsFoldPath = originalFolder }()
+	dir, _ := ioutil.TempDir("", "aws-login-hook")
+	defer os.RemoveAll(dir)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	aws = awslogin.NewMockAWS(ctrl)
+
+	globalHooks = HookConfig{Pre: []string{writeHook(t, dir, "fail", "exit 1\n")}, Timeout: time.Second}
+	defer func() { globalHooks = HookConfig{} }()
+
+	_, err := login("dev", "123456", false)
+	assert.Equal(t, ExitHookFailed, exitCode(err))
+}
+
+func TestRunHooksDryRun(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "aws-login-hook")
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+	hook := writeHook(t, dir, "ok", "touch "+out+"\n")
+
+	dryRun = true
+	defer func() { dryRun, dryRunSkipped = false, nil }()
+	assert.NoError(t, HookConfig{Timeout: time.Second}.runHooks(HookStagePost, []string{hook}, nil))
+	_, err := os.Stat(out)
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, []string{HookStagePost + " hook \"" + hook + "\""}, dryRunSkipped)
+}
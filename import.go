@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/ini.v1"
+)
+
+const Yes = "yes"
+
+const (
+	FromAWSCLI   = "aws-cli"
+	FromAWSVault = "aws-vault"
+)
+
+var ImportCommand = &cli.Command{
+	Name:   "import",
+	Usage:  "import profiles set up by aws cli (mfa_serial, role_arn, source_profile) or aws-vault",
+	Action: importAction,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:    Yes,
+			Aliases: []string{"y"},
+			Usage:   "apply the plan without confirmation",
+		},
+	},
+}
+
+// awsVaultValueFlags are flags of aws-vault exec followed by a value, the value is not the profile
+var awsVaultValueFlags = map[string]bool{
+	"-d":            true,
+	"--duration":    true,
+	"-t":            true,
+	"--mfa-token":   true,
+	"--region":      true,
+	"--prompt":      true,
+	"-b":            true,
+	"--backend":     true,
+	"--keychain":    true,
+	"--pass-dir":    true,
+	"--pass-cmd":    true,
+	"--pass-prefix": true,
+	"--file-dir":    true,
+}
+
+// awsVaultProfile gets profile credential_process executes with aws-vault, empty if it doesn't run aws-vault exec
+func awsVaultProfile(process string) string {
+	fields := strings.Fields(process)
+	for i, field := range fields {
+		if strings.TrimSuffix(filepath.Base(field), ".exe") != "aws-vault" {
+			continue
+		}
+		// the first argument is the command, exec, and the second is the profile
+		var positional []string
+		args := fields[i+1:]
+		for j := 0; j < len(args) && len(positional) < 2; j++ {
+			switch {
+			case args[j] == "--":
+				positional = append(positional, args[j+1:]...)
+				j = len(args)
+			case strings.HasPrefix(args[j], "-"):
+				// --flag=value carries its value
+				if awsVaultValueFlags[args[j]] {
+					j++
+				}
+			default:
+				positional = append(positional, args[j])
+			}
+		}
+		if len(positional) >= 2 && positional[0] == "exec" {
+			return positional[1]
+		}
+		return ""
+	}
+	return ""
+}
+
+// ImportPlan describes how a profile is turned into a profile managed by aws-login
+type ImportPlan struct {
+	Profile  string   `json:"profile"`
+	Kind     string   `json:"kind"`
+	From     string   `json:"from"`
+	Changes  []string `json:"changes"`
+	Warnings []string `json:"warnings,omitempty"`
+
+	apply func(c *Config) error
+}
+
+// ImportResult is printed after import
+type ImportResult struct {
+	Plans        []*ImportPlan `json:"plans"`
+	Applied      bool          `json:"applied"`
+	FilesChanged []string      `json:"files_changed"`
+}
+
+// planImport finds profiles could be managed by aws-login in config
+func planImport(c *Config) []*ImportPlan {
+	plans := make([]*ImportPlan, 0)
+	for _, section := range c.Conf.Sections() {
+		sectionName := section.Name()
+		profile := awslogin.ShortSectionName(sectionName)
+		if sectionName == ini.DefaultSection || strings.HasSuffix(profile, excludeConfigPostfix) || section.HasKey("c_source_profile") || section.HasKey("c_role_arn") {
+			// roles are already managed by aws-login
+			continue
+		}
+
+		from := FromAWSCLI
+		get := func(key string) string {
+			return section.Key(key).String()
+		}
+		if vaultProfile := awsVaultProfile(get("credential_process")); vaultProfile != "" {
+			from = FromAWSVault
+			// aws-vault keeps settings in the profile it executes, which may not be this one
+			if vault, err := c.loadConfigSection(vaultProfile); err == nil && vault != section {
+				get = func(key string) string {
+					if section.HasKey(key) {
+						return section.Key(key).String()
+					}
+					return vault.Key(key).String()
+				}
+			}
+		}
+
+		roleArn, source, serial := get("role_arn"), get("source_profile"), get("mfa_serial")
+		duration, credentialSource := get("duration_seconds"), get("credential_source")
+		switch {
+		case roleArn != "" && source != "":
+			plans = append(plans, planRoleImport(c, sectionName, from, roleArn, source, serial, duration))
+		case roleArn != "" && credentialSource != "":
+			plans = append(plans, planSourceRoleImport(sectionName, from, roleArn, credentialSource, serial, duration))
+		case serial != "":
+			if from == FromAWSCLI && hasNoMFACredential(c, profile) {
+				// already managed by aws-login
+				continue
+			}
+			plans = append(plans, planMFAImport(c, sectionName, from, serial, duration))
+		}
+	}
+	return plans
+}
+
+// loadConfigSection gets "[profile <name>]" or "[<name>]" in config
+func (c *Config) loadConfigSection(name string) (*ini.Section, error) {
+	if section, err := c.Conf.GetSection(Profile + " " + name); err == nil {
+		return section, nil
+	}
+	return c.Conf.GetSection(name)
+}
+
+// importDuration converts duration_seconds of aws cli, default duration is used if not set or invalid
+func importDuration(duration string) int64 {
+	if d, err := strconv.ParseInt(duration, 10, 64); err == nil && d >= 900 {
+		return d
+	}
+	return DefaultDurationSeconds
+}
+
+func planMFAImport(c *Config, sectionName string, from string, serial string, duration string) *ImportPlan {
+	profile := awslogin.ShortSectionName(sectionName)
+	plan := &ImportPlan{
+		Profile: profile,
+		Kind:    MFA,
+		From:    from,
+		Changes: []string{
+			fmt.Sprintf("config [%s]: set mfa_serial = %s, duration = %d", Profile+" "+profile, serial, importDuration(duration)),
+		},
+	}
+	if from == FromAWSVault {
+		plan.Changes = append(plan.Changes, fmt.Sprintf("config [%s]: remove credential_process", sectionName))
+	}
+
+	var credData SessionCredential
+	if section, err := c.GetNoMFACredential(profile); err == nil {
+		_ = section.MapTo(&credData)
+	}
+	if credData.AccessKey != "" && credData.SessionToken == "" {
+		plan.Changes = append(plan.Changes, fmt.Sprintf("credentials: copy long-term keys of [%s] to [%s%s]", profile, profile, excludeConfigPostfix))
+	} else {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("long-term keys not found in credentials, add them to [%s%s]", profile, excludeConfigPostfix))
+		if from == FromAWSVault {
+			plan.Warnings = append(plan.Warnings, "keys are kept by aws-vault, you could print them with `aws-vault export "+profile+"`")
+		}
+	}
+
+	plan.apply = func(c *Config) error {
+		section := c.Conf.Section(sectionName)
+		if from == FromAWSVault {
+			section.DeleteKey("credential_process")
+		}
+		section.DeleteKey("duration_seconds")
+		configData, err := c.LoadConfig(profile)
+		if err != nil {
+			configData = &ConfigData{}
+		}
+		configData.SerialNumber = serial
+		configData.DurationSeconds = importDuration(duration)
+		if err := c.BackupNoMFACredential(profile); err != nil {
+			return err
+		}
+		return c.SaveConfig(configData, profile)
+	}
+	return plan
+}
+
+func planRoleImport(c *Config, sectionName string, from string, roleArn string, source string, serial string, duration string) *ImportPlan {
+	profile := awslogin.ShortSectionName(sectionName)
+	plan := &ImportPlan{
+		Profile: profile,
+		Kind:    Role,
+		From:    from,
+		Changes: []string{
+			fmt.Sprintf("config [%s]: set c_role_arn = %s, c_source_profile = %s, duration = %d", Profile+" "+profile, roleArn, source, importDuration(duration)),
+			fmt.Sprintf("config [%s]: remove role_arn, source_profile, duration_seconds", sectionName),
+		},
+	}
+	if from == FromAWSVault {
+		plan.Changes[1] += ", credential_process"
+	}
+	if serial != "" {
+		plan.Changes[0] += ", mfa_serial = " + serial
+	}
+	if _, err := c.LoadSection(source, c.Cred); err != nil {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("source profile %q not found in credentials", source))
+	}
+
+	plan.apply = func(c *Config) error {
+		section := c.Conf.Section(sectionName)
+		for _, key := range []string{"role_arn", "source_profile", "duration_seconds"} {
+			section.DeleteKey(key)
+		}
+		if from == FromAWSVault {
+			section.DeleteKey("credential_process")
+		}
+		configData, err := c.LoadConfig(profile)
+		if err != nil {
+			configData = &ConfigData{}
+		}
+		configData.AssumeRoleArn = roleArn
+		configData.SourceProfile = source
+		configData.SerialNumber = serial
+		configData.DurationSeconds = importDuration(duration)
+		return c.SaveConfig(configData, profile)
+	}
+	return plan
+}
+
+// planSourceRoleImport moves role_arn of role profile with credential_source to c_role_arn,
+// credential_source is kept as aws-login reads it as is.
+func planSourceRoleImport(sectionName string, from string, roleArn string, credentialSource string, serial string, duration string) *ImportPlan {
+	profile := awslogin.ShortSectionName(sectionName)
+	plan := &ImportPlan{
+		Profile: profile,
+		Kind:    Role,
+		From:    from,
+		Changes: []string{
+			fmt.Sprintf("config [%s]: set c_role_arn = %s, duration = %d", Profile+" "+profile, roleArn, importDuration(duration)),
+			fmt.Sprintf("config [%s]: remove role_arn, duration_seconds", sectionName),
+		},
+	}
+	if serial != "" {
+		plan.Changes[0] += ", mfa_serial = " + serial
+	}
+	if !awslogin.IsCredentialSource(credentialSource) {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("credential source %q is not one of %s", credentialSource, strings.Join(awslogin.CredentialSources, ", ")))
+	}
+
+	plan.apply = func(c *Config) error {
+		section := c.Conf.Section(sectionName)
+		for _, key := range []string{"role_arn", "duration_seconds"} {
+			section.DeleteKey(key)
+		}
+		configData, err := c.LoadConfig(profile)
+		if err != nil {
+			configData = &ConfigData{}
+		}
+		configData.AssumeRoleArn = roleArn
+		configData.CredentialSource = credentialSource
+		configData.SerialNumber = serial
+		configData.DurationSeconds = importDuration(duration)
+		return c.SaveConfig(configData, profile)
+	}
+	return plan
+}
+
+// confirm asks user yes or no, false if not interactive
+func confirm(question string) bool {
+	if !isInteractive() {
+		return false
+	}
+	fmt.Print(a.Bold(a.BrightCyan(question + " [y/N]: ")))
+	text, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer := strings.ToLower(strings.TrimSpace(text))
+	return answer == "y" || answer == "yes"
+}
+
+func printImportPlans(plans []*ImportPlan) {
+	if len(plans) == 0 {
+		fmt.Println("nothing to import")
+		return
+	}
+	for _, plan := range plans {
+		fmt.Printf("%s %s (%s profile from %s)\n", a.Bold("*"), a.Bold(plan.Profile), plan.Kind, plan.From)
+		for _, change := range plan.Changes {
+			fmt.Printf("    %s\n", change)
+		}
+		for _, warning := range plan.Warnings {
+			fmt.Printf("    %s\n", a.Yellow("! "+warning))
+		}
+	}
+}
+
+func importAction(c *cli.Context) error {
+	config, err := NewConfig(awsFoldPath)
+	if err != nil {
+		return err
+	}
+	plans := planImport(config)
+	result := &ImportResult{Plans: plans}
+
+	if outputFormat == OutputText {
+		printImportPlans(plans)
+	}
+	if len(plans) > 0 && (c.Bool(Yes) || confirm("Apply the plan?")) {
+		for _, plan := range plans {
+			if err := plan.apply(config); err != nil {
+				return fmt.Errorf("failed to import %s, %w", plan.Profile, err)
+			}
+		}
+		result.Applied = true
+	}
+	result.FilesChanged = config.ChangedFiles()
+	printResult(result)
+	if len(plans) > 0 && !result.Applied && outputFormat == OutputText {
+		fmt.Println("not applied, run with --yes to apply")
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/sixleaveakkm/aws-login/internal/awstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanImport(t *testing.T) {
+	dir := awstest.WriteFolder(t, `
+[profile dev]
+region = us-east-1
+mfa_serial = arn:aws:iam::123456789012:mfa/user
+
+[profile admin]
+role_arn = arn:aws:iam::210987654321:role/admin
+source_profile = dev
+mfa_serial = arn:aws:iam::123456789012:mfa/user
+duration_seconds = 3600
+
+[profile ci]
+role_arn = arn:aws:iam::210987654321:role/deploy
+credential_source = Environment
+
+[profile vault]
+credential_process = aws-vault exec --no-session vault-source --json
+
+[profile vault-duration]
+credential_process = aws-vault exec --duration 1h vault-source --json
+
+[profile vault-source]
+mfa_serial = arn:aws:iam::123456789012:mfa/vault
+
+[profile managed]
+mfa_serial = arn:aws:iam::123456789012:mfa/user
+
+[profile managed-role]
+c_role_arn = arn:aws:iam::210987654321:role/deploy
+credential_source = Ec2InstanceMetadata
+mfa_serial = arn:aws:iam::123456789012:mfa/user
+
+[profile plain]
+region = us-east-1
+`, `
+[dev]
+aws_access_key_id = KEY
+aws_secret_access_key = SECRET
+
+[managed_no_mfa]
+aws_access_key_id = KEY
+aws_secret_access_key = SECRET
+`)
+	defer os.RemoveAll(dir)
+
+	config := mustNewConfig(t, dir)
+	plans := planImport(config)
+	assert.Len(t, plans, 6)
+	byProfile := make(map[string]*ImportPlan)
+	for _, p := range plans {
+		byProfile[p.Profile] = p
+	}
+	assert.Equal(t, MFA, byProfile["dev"].Kind)
+	assert.Empty(t, byProfile["dev"].Warnings)
+	assert.Equal(t, Role, byProfile["admin"].Kind)
+	assert.Equal(t, FromAWSVault, byProfile["vault"].From)
+	assert.NotEmpty(t, byProfile["vault"].Warnings)
+	assert.Equal(t, FromAWSCLI, byProfile["vault-source"].From)
+
+	dryRun, config.DryRun = true, true
+	defer func() { dryRun, dryRunConfigs = false, nil }()
+	for _, p := range plans {
+		assert.NoError(t, p.apply(config))
+	}
+	assert.Equal(t, "KEY", config.Cred.Section("dev_no_mfa").Key("aws_access_key_id").String())
+	assert.Equal(t, "43200", config.Conf.Section("profile dev").Key("duration").String())
+
+	admin := config.Conf.Section("profile admin")
+	assert.Equal(t, "arn:aws:iam::210987654321:role/admin", admin.Key("c_role_arn").String())
+	assert.Equal(t, "dev", admin.Key("c_source_profile").String())
+	assert.Equal(t, "3600", admin.Key("duration").String())
+	assert.False(t, admin.HasKey("role_arn"))
+	assert.False(t, admin.HasKey("source_profile"))
+
+	ci := config.Conf.Section("profile ci")
+	assert.Equal(t, "arn:aws:iam::210987654321:role/deploy", ci.Key("c_role_arn").String())
+	assert.Equal(t, "Environment", ci.Key("credential_source").String())
+	assert.False(t, ci.HasKey("role_arn"))
+
+	vault := config.Conf.Section("profile vault")
+	assert.Equal(t, "arn:aws:iam::123456789012:mfa/vault", vault.Key("mfa_serial").String())
+	assert.False(t, vault.HasKey("credential_process"))
+	vaultDuration := config.Conf.Section("profile vault-duration")
+	assert.Equal(t, "arn:aws:iam::123456789012:mfa/vault", vaultDuration.Key("mfa_serial").String())
+
+	for _, p := range planImport(config) {
+		assert.NotEqual(t, "admin", p.Profile, "imported role must not be planned again")
+		assert.NotEqual(t, "ci", p.Profile, "imported role must not be planned again")
+		assert.NotEqual(t, "dev", p.Profile, "imported mfa must not be planned again")
+	}
+}
+
+func TestAWSVaultProfile(t *testing.T) {
+	tests := []struct {
+		process string
+		want    string
+	}{
+		{"aws-vault exec prod --json", "prod"},
+		{"aws-vault exec --no-session prod --json", "prod"},
+		{"aws-vault exec --duration 1h prod --json", "prod"},
+		{"aws-vault exec -d 1h --region us-east-1 prod --json", "prod"},
+		{"aws-vault exec --duration=1h prod --json", "prod"},
+		{"/usr/local/bin/aws-vault --backend file exec prod --json", "prod"},
+		{"/usr/local/bin/aws-vault exec -- prod", "prod"},
+		{"aws-vault exec --json", ""},
+		{"aws-vault login prod", ""},
+		{"other-tool exec prod", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, awsVaultProfile(tt.process), tt.process)
+	}
+}
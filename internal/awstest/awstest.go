@@ -0,0 +1,22 @@
+// Package awstest has helpers of tests reading aws folders, shared by main and awslogin packages.
+package awstest
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// WriteFolder writes config and credentials files into a new temp folder, the caller removes it
+func WriteFolder(t *testing.T, config string, credentials string) string {
+	dir, err := ioutil.TempDir("", "aws-login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"config": config, "credentials": credentials} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/ini.v1"
+)
+
+var ListCommand = &cli.Command{
+	Name:   "list",
+	Usage:  "list profiles managed by aws-login with their session state",
+	Action: listAction,
+}
+
+const (
+	StateValid       = "valid"
+	StateExpired     = "expired"
+	StateNoSession   = "no_session"
+	StateUnknownTime = "unknown"
+)
+
+// ProfileInfo describes a profile managed by aws-login
+type ProfileInfo struct {
+	Profile       string `json:"profile"`
+	Kind          string `json:"kind"`
+	Identity      string `json:"identity"`
+	SourceProfile string `json:"source_profile,omitempty"`
+	// CredentialSource of role profiles without source profile
+	CredentialSource string     `json:"credential_source,omitempty"`
+	Region           string     `json:"region,omitempty"`
+	Account          string     `json:"account,omitempty"`
+	AccountAlias     string     `json:"account_alias,omitempty"`
+	Expiry           *time.Time `json:"expiry,omitempty"`
+	State            string     `json:"state"`
+}
+
+// listManagedProfiles lists mfa and role profiles in config, sorted as in config file.
+// Profiles without mfa_serial, c_source_profile or credential_source are not managed by aws-login.
+func (c *Config) listManagedProfiles() []ProfileInfo {
+	results := make([]ProfileInfo, 0)
+	for _, section := range c.Conf.Sections() {
+		name := awslogin.ShortSectionName(section.Name())
+		if name == ini.DefaultSection || strings.HasSuffix(name, excludeConfigPostfix) {
+			continue
+		}
+		var conf ConfigData
+		if err := section.MapTo(&conf); err != nil {
+			continue
+		}
+		info := ProfileInfo{Profile: name, Region: conf.Region, State: StateNoSession}
+		switch {
+		case conf.IsRole():
+			info.Kind = Role
+			info.Identity = conf.AssumeRoleArn
+			info.SourceProfile = conf.SourceProfile
+			info.CredentialSource = conf.CredentialSource
+		case conf.SerialNumber != "":
+			info.Kind = MFA
+			info.Identity = conf.SerialNumber
+		default:
+			continue
+		}
+		if session, err := c.LoadSession(name); err == nil {
+			info.State = sessionState(session)
+			if !session.Expiration.IsZero() {
+				info.Expiry = &session.Expiration
+			}
+		}
+		identity := c.loadIdentityCache(name)
+		info.Account, info.AccountAlias = identity.AccountID, identity.AccountAlias
+		results = append(results, info)
+	}
+	return results
+}
+
+// sessionState tells whether the session credential could still be used
+func sessionState(cred *SessionCredential) string {
+	if cred.SessionToken == "" {
+		return StateNoSession
+	}
+	if cred.Expiration.IsZero() {
+		return StateUnknownTime
+	}
+	if time.Now().After(cred.Expiration) {
+		return StateExpired
+	}
+	return StateValid
+}
+
+func listAction(_ *cli.Context) error {
+	config, err := NewConfig(awsFoldPath)
+	if err != nil {
+		return err
+	}
+	profiles := config.listManagedProfiles()
+	if outputFormat == OutputJSON {
+		printJSON(profiles)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PROFILE\tKIND\tACCOUNT\tIDENTITY\tEXPIRY\tSTATE")
+	for _, p := range profiles {
+		expiry := "-"
+		if p.Expiry != nil {
+			expiry = p.Expiry.Local().Format(time.RFC3339)
+		}
+		state := p.State
+		switch state {
+		case StateValid:
+			state = a.Green(state).String()
+		case StateExpired:
+			state = a.Red(state).String()
+		}
+		account := "-"
+		if p.AccountAlias != "" {
+			account = p.AccountAlias
+		} else if p.Account != "" {
+			account = p.Account
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", p.Profile, p.Kind, account, p.Identity, expiry, state)
+	}
+	return w.Flush()
+}
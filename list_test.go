@@ -0,0 +1,83 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sixleaveakkm/aws-login/internal/awstest"
+	"github.com/stretchr/testify/assert"
+)
+
+// mfaFolder has profile dev with mfa_serial and its long-term keys in dev_no_mfa,
+// saves of login go to debugOutputFolder so it is not changed
+const mfaFolder = "./test_resource/mfa"
+
+// mustNewConfig loads files in folder, the test fails if they could not be read
+func mustNewConfig(t *testing.T, folder string) *Config {
+	config, err := NewConfig(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+// setOutputFolder saves files to a temp folder instead of the shared output folder, call the returned func to restore
+func setOutputFolder(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "aws-login-output")
+	assert.NoError(t, err)
+	original := debugOutputFolder
+	debugOutputFolder = dir
+	return dir, func() {
+		debugOutputFolder = original
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestListManagedProfiles(t *testing.T) {
+	expiry := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	dir := awstest.WriteFolder(t, `
+[profile dev]
+region = us-east-1
+mfa_serial = arn:aws:iam::123456789012:mfa/user
+
+[profile admin]
+mfa_serial = arn:aws:iam::123456789012:mfa/user
+c_source_profile = dev
+c_role_arn = arn:aws:iam::210987654321:role/admin
+
+[profile plain]
+region = us-east-1
+`, `
+[dev_no_mfa]
+aws_access_key_id = KEY
+aws_secret_access_key = SECRET
+
+[dev]
+aws_access_key_id = SESSION_KEY
+aws_secret_access_key = SESSION_SECRET
+aws_session_token = TOKEN
+aws_expiration = `+expiry+`
+`)
+	defer os.RemoveAll(dir)
+
+	profiles := mustNewConfig(t, dir).listManagedProfiles()
+	assert.Len(t, profiles, 2)
+	assert.Equal(t, "dev", profiles[0].Profile)
+	assert.Equal(t, MFA, profiles[0].Kind)
+	assert.Equal(t, StateValid, profiles[0].State)
+	assert.Equal(t, expiry, profiles[0].Expiry.UTC().Format(time.RFC3339))
+
+	assert.Equal(t, "admin", profiles[1].Profile)
+	assert.Equal(t, Role, profiles[1].Kind)
+	assert.Equal(t, "arn:aws:iam::210987654321:role/admin", profiles[1].Identity)
+	assert.Equal(t, StateNoSession, profiles[1].State)
+}
+
+func Test_sessionState(t *testing.T) {
+	assert.Equal(t, StateNoSession, sessionState(&SessionCredential{AccessKey: "KEY"}))
+	assert.Equal(t, StateUnknownTime, sessionState(&SessionCredential{SessionToken: "TOKEN"}))
+	assert.Equal(t, StateExpired, sessionState(&SessionCredential{SessionToken: "TOKEN", Expiration: time.Now().Add(-time.Minute)}))
+	assert.Equal(t, StateValid, sessionState(&SessionCredential{SessionToken: "TOKEN", Expiration: time.Now().Add(time.Minute)}))
+}
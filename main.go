@@ -2,10 +2,10 @@ package main
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"regexp"
 
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
 	"github.com/urfave/cli/v2"
 	"gopkg.in/ini.v1"
 )
@@ -22,24 +22,28 @@ const (
 	SourceProfile      = "source-profile"
 	RoleArn            = "role-arn"
 	NoMFA              = "no-mfa"
+	SourceEnv          = "source-env"
+	CredentialSource   = "credential-source"
 	// DefaultDurationSeconds 12 hours
 	DefaultDurationSeconds = 43200
 )
 
 var (
 	aws AWS
+	// sourceEnv assumes roles with credentials in environments instead of source profiles
+	sourceEnv bool
 )
 
 func init() {
 	ini.PrettyEqual = true
 	ini.PrettyFormat = false
 
-	aws = AWSImpl{}
+	aws = awslogin.AWSImpl{}
 	setAWSFolderDefault()
 }
 
 func main() {
-	executor(os.Args)
+	executor(dockerHelperArgs(os.Args))
 }
 
 func executor(args []string) {
@@ -48,7 +52,7 @@ func executor(args []string) {
 		Usage:                "login your aws cli",
 		Version:              Version,
 		EnableBashCompletion: true,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&cli.StringFlag{
 				Name:    "profile",
 				Aliases: []string{"p"},
@@ -61,7 +65,18 @@ func executor(args []string) {
 				Usage:   "profile set as default",
 				Value:   false,
 			},
-		},
+			&cli.BoolFlag{
+				Name:  SourceEnv,
+				Usage: "assume role with credentials in environments (AWS_ACCESS_KEY_ID, ...) instead of source of the profile",
+			},
+			outputFlag,
+			debugFlag,
+			dryRunFlag,
+			offlineFlag,
+			cacheOnlyFlag,
+		}, append(clientFlags, hookFlags...)...),
+		Before:       beforeAction,
+		After:        printDryRunDiffs,
 		Action:       loginAction,
 		BashComplete: loginBashComplete,
 		Commands: []*cli.Command{
@@ -75,14 +90,48 @@ func executor(args []string) {
 				Action:       configAction,
 				BashComplete: configBashComplete,
 			},
+			ListCommand,
+			DoctorCommand,
+			ImportCommand,
+			ExportCommand,
+			ShellInitCommand,
+			UseCommand,
+			StatusCommand,
+			CompletionCommand,
+			MFADeviceCommand,
+			WhoamiCommand,
+			ConsoleCommand,
+			AgentCommand,
+			CredentialProcessCommand,
+			EnvCommand,
+			ExecCommand,
+			EKSCommand,
+			VaultPayloadCommand,
+			DockerCredentialCommand,
 		},
 	}
 	err := app.Run(args)
 	if err != nil {
-		log.Fatal(err)
+		if outputFormat == OutputJSON {
+			printJSON(newErrorResult(err))
+		} else {
+			printError(err)
+		}
+		os.Exit(exitCode(err))
 	}
 }
 
+// beforeAction applies global flags before any action runs
+func beforeAction(c *cli.Context) error {
+	debugLog = c.Bool(Debug)
+	dryRun = c.Bool(DryRun)
+	cacheOnly = c.Bool(CacheOnly)
+	sourceEnv = c.Bool(SourceEnv)
+	loadGlobalClientConfig(c)
+	loadGlobalHookConfig(c)
+	return loadOutputOptions(c)
+}
+
 // configAction for `aws-login config` which only contains two sub-commands
 func configAction(c *cli.Context) error {
 	return cli.ShowAppHelp(c)
@@ -98,28 +147,92 @@ func isSixDigit(code string) bool {
 // the input profile is checked previously
 func loginAction(c *cli.Context) error {
 	profile := getProfile(c)
-	code := c.Args().Get(0)
-	if !isSixDigit(code) {
-		if code == "" {
-			code = promptSixDigitCode()
-		} else {
-			return fmt.Errorf("input code must be 6 digit, got '%s'", code)
+	code, err := getCode(profile, c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+
+	result, err := login(profile, code, c.Bool("default"))
+	if err != nil {
+		return err
+	}
+	printResult(result)
+	return nil
+}
+
+// getCode checks code given as argument, prompt for it if not given and profile needs one
+func getCode(profile string, code string) (string, error) {
+	if isSixDigit(code) {
+		return code, nil
+	}
+	if code == "" {
+		if !needsMFACode(profile) {
+			return "", nil
 		}
+		return promptSixDigitCode()
 	}
+	return "", fmt.Errorf("input code must be 6 digit, got '%s'", code)
+}
 
-	config := NewConfig(awsFoldPath)
-	confSection, err := config.Conf.GetSection(Profile + " " + profile)
+// needsMFACode checks whether login of profile needs mfa code, roles without mfa_serial don't.
+// Unknown profile is reported by login instead.
+func needsMFACode(profile string) bool {
+	config, err := NewConfig(awsFoldPath)
 	if err != nil {
-		scriptName := os.Args[0]
-		return fmt.Errorf("%q %w\nYou could try:\n\t%s config <mfa|role> ...\n to create config", profile, NoProfileError, scriptName)
+		return true
 	}
-	var confData ConfigData
-	_ = confSection.MapTo(&confData)
+	conf, err := config.ProfileConfig(profile)
+	return err != nil || conf.SerialNumber != ""
+}
 
-	setToDefault := c.Bool("default")
-	if confData.SourceProfile != "" {
-		return loginForRole(config, profile, code, setToDefault)
+// login loads config, and login profile by mfa or role according to its config
+func login(profile string, code string, toDefault bool) (*LoginResult, error) {
+	config, err := NewConfig(awsFoldPath)
+	if err != nil {
+		return nil, err
+	}
+	confData, err := config.ProfileConfig(profile)
+	if err != nil {
+		return nil, &LoginError{Kind: awslogin.ProfileNotFound, Profile: profile, Err: err}
+	}
+
+	kind := MFA
+	if confData.IsRole() || (sourceEnv && confData.AssumeRoleArn != "") {
+		kind = Role
+	}
+	hooks := globalHooks.withProfile(confData)
+	if err := hooks.runHooks(HookStagePre, hooks.Pre, hooks.hookEnv(HookStagePre, profile, kind, confData, nil)); err != nil {
+		return nil, &LoginError{Kind: awslogin.HookFailed, Profile: profile, Err: err}
+	}
+
+	input := &awslogin.LoginInput{Profile: profile, Code: code, Default: toDefault, SourceEnv: sourceEnv, CacheOnly: cacheOnly, Client: globalClient}
+	var cred *SessionCredential
+	identity := confData.SerialNumber
+	if kind == Role {
+		cred, err = awslogin.LoginRole(config.Config, aws, input)
+		identity = confData.AssumeRoleArn
 	} else {
-		return loginForMFA(config, profile, code, setToDefault)
+		cred, err = awslogin.LoginMFA(config.Config, aws, input)
+	}
+	if err != nil {
+		recordMFAFailure(profile, err)
+		return nil, err
+	}
+	result := newLoginResult(config, profile, kind, identity, cred)
+
+	resetMFAFailures(profile)
+	// serial is discovered with settings of the profile it is cached for
+	warmClient := config.clientConfigFor(profile)
+	if confData.SourceProfile != "" {
+		warmClient = config.clientConfigFor(confData.SourceProfile)
+	}
+	defer warmMFACache(profile, confData, warmClient)()
+
+	if err := hooks.runHooks(HookStagePost, hooks.Post, hooks.hookEnv(HookStagePost, profile, kind, confData, cred)); err != nil {
+		if hooks.Strict {
+			return nil, &LoginError{Kind: awslogin.HookFailed, Profile: profile, Err: err}
+		}
+		fmt.Fprintln(os.Stderr, a.Yellow(fmt.Sprintf("! %v, session is saved", err)))
 	}
+	return result, nil
 }
@@ -7,6 +7,8 @@ import (
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	"github.com/sixleaveakkm/aws-login/internal/awstest"
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/ini.v1"
 )
@@ -27,7 +29,7 @@ func TestConfigMFA(t *testing.T) {
 	// test config mfa success
 	args := []string{"aws-login", "config", "mfa", "-p", "user-profile", "-n", "arn"}
 	executor(args)
-	outConfig := NewConfig(filepath.Join(debugAwsFolderPath, "output"))
+	outConfig := mustNewConfig(t, filepath.Join(debugAwsFolderPath, "output"))
 	fmt.Println(outConfig.Conf.Section("user-profile").Key("mfa_serial").String())
 	assert.Equal(t, "arn", outConfig.Conf.Section("user-profile").Key("mfa_serial").String())
 	assert.Equal(t, "43200", outConfig.Conf.Section("user-profile").Key("duration").String())
@@ -36,7 +38,7 @@ func TestConfigMFA(t *testing.T) {
 	// test config mfa override
 	args = []string{"aws-login", "config", "mfa", "-p", "profile-exist", "-n", "arn:another", "-t", "30000"}
 	executor(args)
-	outConfig = NewConfig(filepath.Join(debugAwsFolderPath, "output"))
+	outConfig = mustNewConfig(t, filepath.Join(debugAwsFolderPath, "output"))
 	assert.Equal(t, "arn:another", outConfig.Conf.Section("profile-exist").Key("mfa_serial").String())
 	assert.Equal(t, "30000", outConfig.Conf.Section("profile-exist").Key("duration").String())
 }
@@ -44,7 +46,7 @@ func TestConfigMFA(t *testing.T) {
 func TestMFALogin(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
-	m := NewMockAWS(ctrl)
+	m := awslogin.NewMockAWS(ctrl)
 	m.EXPECT().GetMFASession(gomock.Any()).Return(&SessionCredential{
 		AccessKey:    "MFA_KEY_ID",
 		SecretKey:    "MFA_SECRET",
@@ -54,7 +56,7 @@ func TestMFALogin(t *testing.T) {
 
 	args := []string{"aws-login", "-p", "dummy", "-d", "123456"}
 	executor(args)
-	outConfig := NewConfig(filepath.Join(debugAwsFolderPath, "output"))
+	outConfig := mustNewConfig(t, filepath.Join(debugAwsFolderPath, "output"))
 	assert.Equal(t, "MFA_KEY_ID",
 		outConfig.Cred.Section("dummy").Key("aws_access_key_id").String())
 	assert.Equal(t, "MFA_SESSION_TOKEN",
@@ -66,11 +68,35 @@ func TestMFALogin(t *testing.T) {
 		outConfig.Cred.Section("default").Key("aws_session_token").String())
 }
 
+func TestConfigMFADiscovery(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := awslogin.NewMockAWS(ctrl)
+	m.EXPECT().ListMFADevices("user-profile", gomock.Any()).Return([]MFADevice{
+		{SerialNumber: "arn:aws:iam::123456789012:mfa/only", Virtual: true},
+	}, nil)
+	aws = m
+
+	// the only device is used when serial number is not given
+	args := []string{"aws-login", "config", "mfa", "-p", "user-profile"}
+	executor(args)
+	outConfig := mustNewConfig(t, filepath.Join(debugAwsFolderPath, "output"))
+	assert.Equal(t, "arn:aws:iam::123456789012:mfa/only", outConfig.Conf.Section("profile user-profile").Key("mfa_serial").String())
+
+	// several devices need to be picked, which is impossible without terminal
+	m.EXPECT().ListMFADevices("user-profile", gomock.Any()).Return([]MFADevice{
+		{SerialNumber: "arn:aws:iam::123456789012:mfa/phone", Virtual: true},
+		{SerialNumber: "GAHT12345678"},
+	}, nil)
+	_, err := discoverMFASerial(mustNewConfig(t, awsFoldPath), "user-profile")
+	assert.Error(t, err)
+}
+
 func TestConfigRole(t *testing.T) {
 	// test config mfa success
 	args := []string{"aws-login", "config", "role", "-p", "user-role", "-s", "user-profile", "-n", "arn", "-r", "arn:dummy-role"}
 	executor(args)
-	outConfig := NewConfig(filepath.Join(debugAwsFolderPath, "output"))
+	outConfig := mustNewConfig(t, filepath.Join(debugAwsFolderPath, "output"))
 	assert.Equal(t, "arn", outConfig.Conf.Section("user-role").Key("mfa_serial").String())
 	assert.Equal(t, "43200", outConfig.Conf.Section("user-role").Key("duration").String())
 	assert.Equal(t, "arn:dummy-role", outConfig.Conf.Section("user-role").Key("role_arn").String())
@@ -80,7 +106,7 @@ func TestConfigRole(t *testing.T) {
 func TestRoleLogin(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
-	m := NewMockAWS(ctrl)
+	m := awslogin.NewMockAWS(ctrl)
 	m.EXPECT().GetAssumeRoleSession(gomock.Any()).Return(&SessionCredential{
 		AccessKey:    "MFA_KEY_ID",
 		SecretKey:    "MFA_SECRET",
@@ -90,7 +116,7 @@ func TestRoleLogin(t *testing.T) {
 
 	args := []string{"aws-login", "-p", "user-role-2", "-d", "123456"}
 	executor(args)
-	outConfig := NewConfig(filepath.Join(debugAwsFolderPath, "output"))
+	outConfig := mustNewConfig(t, filepath.Join(debugAwsFolderPath, "output"))
 	assert.Equal(t, "MFA_KEY_ID",
 		outConfig.Cred.Section("user-role-2").Key("aws_access_key_id").String())
 	assert.Equal(t, "MFA_SESSION_TOKEN",
@@ -102,6 +128,49 @@ func TestRoleLogin(t *testing.T) {
 		outConfig.Cred.Section("default").Key("aws_session_token").String())
 }
 
+func TestGetCodeWithoutMFASerial(t *testing.T) {
+	dir := awstest.WriteFolder(t, `
+[profile ci]
+c_role_arn = arn:aws:iam::210987654321:role/deploy
+credential_source = Environment
+
+[profile dev]
+mfa_serial = arn:aws:iam::123456789012:mfa/user
+`, "")
+	defer os.RemoveAll(dir)
+	originalFolder := awsFoldPath
+	awsFoldPath = dir
+	defer func() { awsFoldPath = originalFolder }()
+
+	code, err := getCode("ci", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "", code)
+	// tests run without terminal, code could not be prompted
+	_, err = getCode("dev", "")
+	assert.Error(t, err)
+	code, err = getCode("dev", "123456")
+	assert.NoError(t, err)
+	assert.Equal(t, "123456", code)
+}
+
+func TestConfigRoleCredentialSource(t *testing.T) {
+	dir := awstest.WriteFolder(t, "", "")
+	defer os.RemoveAll(dir)
+	originalFolder := awsFoldPath
+	awsFoldPath = dir
+	defer func() { awsFoldPath = originalFolder }()
+	output, restore := setOutputFolder(t)
+	defer restore()
+
+	executor([]string{"aws-login", "config", "role", "-p", "deploy", "-r", "arn:aws:iam::210987654321:role/deploy", "--credential-source", "Ec2InstanceMetadata"})
+	saved, err := ini.Load(filepath.Join(output, configFile_))
+	assert.NoError(t, err)
+	section := saved.Section("profile deploy")
+	assert.Equal(t, "Ec2InstanceMetadata", section.Key("credential_source").String())
+	// chained sessions are capped at 1 hour, the default 12 hours is not written
+	assert.False(t, section.HasKey("duration"))
+}
+
 func Test_isSixDigit(t *testing.T) {
 	tests := []struct {
 		name string
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
 	"github.com/urfave/cli/v2"
 )
 
@@ -34,13 +35,16 @@ var MFACommand = &cli.Command{
 
 // configMFAAction is action function for `aws-login config mfa`
 func configMFAAction(c *cli.Context) error {
-	config := NewConfig(awsFoldPath)
+	config, err := NewConfig(awsFoldPath)
+	if err != nil {
+		return err
+	}
 	profile := getProfile(c)
-	if !config.listPossibleProfiles().Contains(ShortSectionName(profile)) {
+	if !config.ListPossibleProfiles().Contains(awslogin.ShortSectionName(profile)) {
 		return errors.New("input profile is not valid")
 	}
 
-	configData, err := config.loadConfig(profile)
+	configData, err := config.LoadConfig(profile)
 	if err != nil {
 		// startMFACUI(configData)
 		return fmt.Errorf("failed to load profile %s", profile)
@@ -57,64 +61,57 @@ func configMFAAction(c *cli.Context) error {
 
 	serial := c.String(SerialNumber)
 	if serial == "" {
-		return fmt.Errorf("serial-number cannot be blank")
+		if serial, err = discoverMFASerial(config, profile); err != nil {
+			return err
+		}
 	}
 
+	if err := configureMFA(config, configData, profile, serial); err != nil {
+		return err
+	}
+	printResult(&ConfigResult{Profile: profile, Kind: MFA, FilesChanged: config.ChangedFiles()})
+	return nil
+}
+
+// configureMFA saves serial to profile.
+// If profile is not configured with mfa yet, its credential is backed up to "_no_mfa" first.
+func configureMFA(config *Config, configData *ConfigData, profile string, serial string) error {
 	// SerialNumber exists, old mfa profile already set. over write
 	if configData.SerialNumber != "" {
 		configData.SerialNumber = serial
-		config.saveConfig(configData, profile, configFile_)
-		return nil
+		return config.SaveConfig(configData, profile)
 	}
 	// SerialNumber doesn't exist, backup credential to "_no_mfa" and save
 	// if original profile contains "profile " prefix, no_mfa profile will also has this prefix.
 	configData.SerialNumber = serial
-	config.backupNoMFACredential(profile, credentialsFile_)
-	config.saveConfig(configData, profile, configFile_)
-	return nil
+	if err := config.BackupNoMFACredential(profile); err != nil {
+		return err
+	}
+	return config.SaveConfig(configData, profile)
 }
 
-// func startMFACUI(configData *ConfigDataWithCode) {
-// 	fmt.Println("start mfa cui")
-// }
-
-// loginForMFA
-// <prof> must exists in config, <prof_no_mfa> or <profile prof_no_mfa> exists in credential
-func loginForMFA(config *Config, profile string, code string, toDefault bool) error {
-	profileNoMFA := fmt.Sprintf("%s%s", profile, excludeConfigPostfix)
-	_, err := config.Cred.GetSection(profileNoMFA)
-	if err != nil {
-		profileNoMFA = fmt.Sprintf("profile %s%s", profile, excludeConfigPostfix)
-		_, err = config.Cred.GetSection(profileNoMFA)
-		if err != nil {
-			return NoProfileError
-		}
+// discoverMFASerial finds mfa devices of profile's user when serial number is not given.
+// The only device is used as is, user picks one if there are several.
+func discoverMFASerial(config *Config, profile string) (string, error) {
+	credProfile := profile
+	if section, err := config.GetNoMFACredential(profile); err == nil {
+		credProfile = section.Name()
 	}
-
-	// section <profile> must exists
-	confSection := config.Conf.Section("profile " + profile)
-	var confData ConfigData
-	_ = confSection.MapTo(&confData)
-
-	out, err := aws.GetMFASession(&GetMFASessionInput{
-		Profile:         profileNoMFA,
-		SerialNumber:    confData.SerialNumber,
-		DurationSeconds: confData.DurationSeconds,
-		Code:            code,
-	})
+	devices, err := aws.ListMFADevices(credProfile, config.clientConfigFor(profile))
 	if err != nil {
-		return fmt.Errorf("failed get mfa, %v\n", err)
-	}
-	cred := &SessionCredential{
-		AccessKey:    out.AccessKey,
-		SecretKey:    out.SecretKey,
-		SessionToken: out.SessionToken,
+		return "", fmt.Errorf("serial-number is not given and failed to find mfa devices, %w", err)
 	}
-	config.saveCredential(cred, profile, credentialsFile_)
-
-	if toDefault {
-		config.saveConfig(&confData, "default", configFile_)
-		config.saveCredential(cred, "default", credentialsFile_)
+	debugf("found %d mfa device(s) of %q", len(devices), credProfile)
+	switch len(devices) {
+	case 0:
+		return "", fmt.Errorf("serial-number is not given and no mfa device found for %q", profile)
+	case 1:
+		fmt.Fprintf(infoOut(), "Using mfa device %s\n", describeMFADevice(devices[0]))
+		return devices[0].SerialNumber, nil
 	}
-	return nil
+	return promptMFADevice(devices)
 }
+
+// func startMFACUI(configData *ConfigDataWithCode) {
+// 	fmt.Println("start mfa cui")
+// }
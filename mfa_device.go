@@ -0,0 +1,223 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/urfave/cli/v2"
+	"rsc.io/qr"
+)
+
+const PNG = "png"
+
+const mfaFailuresFile = "mfa_failures.json"
+
+var MFADeviceCommand = &cli.Command{
+	Name:  "mfa",
+	Usage: "manage mfa device of the user of profile",
+	Subcommands: []*cli.Command{
+		{
+			Name:   "register",
+			Usage:  "create and enable a virtual mfa device, then config profile to use it",
+			Action: mfaRegisterAction,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     Profile,
+					Aliases:  []string{"p"},
+					Usage:    "profile whose user gets the device",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:  PNG,
+					Usage: "also save qr code as png to the path, it contains the secret of device",
+				},
+			},
+		},
+		{
+			Name:   "resync",
+			Usage:  "resync mfa device of profile whose codes are rejected, e.g. after clock drift",
+			Action: mfaResyncAction,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     Profile,
+					Aliases:  []string{"p"},
+					Usage:    "mfa or role profile whose device is resynced",
+					Required: true,
+				},
+			},
+		},
+	},
+}
+
+// otpauthURI makes uri read by authenticator apps, in the same form as aws console
+func otpauthURI(device *VirtualMFADevice) string {
+	issuer := "Amazon Web Services"
+	label := url.PathEscape(issuer + ":" + device.UserName + "@" + device.AccountID)
+	query := url.Values{"secret": {device.Seed}, "issuer": {issuer}}
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// writeQR draws code with half blocks, two modules a character, with quiet zone around.
+// Dark modules are drawn as spaces, so it is read on dark terminals as on paper.
+func writeQR(w io.Writer, code *qr.Code) {
+	const quiet = 2
+	black := func(x, y int) bool {
+		if x < 0 || y < 0 || x >= code.Size || y >= code.Size {
+			return false
+		}
+		return code.Black(x, y)
+	}
+	for y := -quiet; y < code.Size+quiet; y += 2 {
+		var line strings.Builder
+		for x := -quiet; x < code.Size+quiet; x++ {
+			top, bottom := black(x, y), black(x, y+1)
+			switch {
+			case top && bottom:
+				line.WriteString(" ")
+			case top:
+				line.WriteString("▄")
+			case bottom:
+				line.WriteString("▀")
+			default:
+				line.WriteString("█")
+			}
+		}
+		fmt.Fprintln(w, line.String())
+	}
+}
+
+// longTermProfile gets name of the section with long-term keys of profile
+func longTermProfile(config *Config, profile string) string {
+	if section, err := config.GetNoMFACredential(profile); err == nil {
+		return section.Name()
+	}
+	return profile
+}
+
+func mfaRegisterAction(c *cli.Context) error {
+	// the device is created in aws before anything is written, dry run could not keep it from happening
+	if dryRun {
+		return fmt.Errorf("mfa register creates a device in aws, it can't run with --%s", DryRun)
+	}
+	if !isInteractive() {
+		return fmt.Errorf("mfa register needs a terminal to show qr code and read codes")
+	}
+	config, err := NewConfig(awsFoldPath)
+	if err != nil {
+		return err
+	}
+	return registerMFADevice(config, c.String(Profile), c.String(PNG), os.Stdout, promptConsecutiveCodes)
+}
+
+// registerMFADevice creates a virtual mfa device for user of profile, shows it on out, and enables it with codes read.
+// The device is deleted if it could not be enabled.
+func registerMFADevice(config *Config, profile string, pngPath string, out io.Writer, readCodes func() (string, string, error)) error {
+	configData, err := config.LoadConfig(profile)
+	if err != nil {
+		configData = &ConfigData{DurationSeconds: DefaultDurationSeconds}
+	}
+	credProfile := longTermProfile(config, profile)
+	client := config.clientConfigFor(profile)
+
+	device, err := aws.CreateVirtualMFADevice(credProfile, client)
+	if err != nil {
+		return awslogin.NewLoginError(profile, "failed to create virtual mfa device", err, false)
+	}
+	debugf("created virtual mfa device %s", device.SerialNumber)
+
+	uri := otpauthURI(device)
+	code, err := qr.Encode(uri, qr.M)
+	if err != nil {
+		return fmt.Errorf("failed to make qr code, %w", err)
+	}
+	if pngPath != "" {
+		if err := writeSecretFile(pngPath, code.PNG()); err != nil {
+			return fmt.Errorf("failed to write %s, %w", pngPath, err)
+		}
+		fmt.Fprintf(out, "qr code saved to %s\n", pngPath)
+	}
+	fmt.Fprintln(out, "Scan the qr code with your authenticator app:")
+	writeQR(out, code)
+	fmt.Fprintf(out, "or enter the secret manually: %s\n", device.Seed)
+
+	code1, code2, err := readCodes()
+	if err == nil {
+		err = aws.EnableMFADevice(credProfile, client, device, code1, code2)
+	}
+	if err != nil {
+		// device not enabled blocks registering again with the same name
+		if e := aws.DeleteVirtualMFADevice(credProfile, client, device.SerialNumber); e != nil {
+			debugf("failed to delete virtual mfa device %s, %v", device.SerialNumber, e)
+		}
+		return awslogin.NewLoginError(profile, "failed to enable mfa device", err, false)
+	}
+
+	if err := configureMFA(config, configData, profile, device.SerialNumber); err != nil {
+		return err
+	}
+	writeMFACache(profile, device.SerialNumber)
+	printResult(&ConfigResult{Profile: profile, Kind: MFA, FilesChanged: config.ChangedFiles()})
+	return nil
+}
+
+func mfaResyncAction(c *cli.Context) error {
+	config, err := NewConfig(awsFoldPath)
+	if err != nil {
+		return err
+	}
+	profile := c.String(Profile)
+	configData, err := config.LoadConfig(profile)
+	if err != nil {
+		return &LoginError{Kind: awslogin.ProfileNotFound, Profile: profile, Err: fmt.Errorf("%q %w", profile, awslogin.ErrProfileNotFound)}
+	}
+	if configData.SerialNumber == "" {
+		return fmt.Errorf("%q has no mfa_serial, nothing to resync", profile)
+	}
+	// device of a role profile belongs to the user of its source profile
+	owner := profile
+	if configData.SourceProfile != "" {
+		owner = configData.SourceProfile
+	}
+	credProfile := longTermProfile(config, owner)
+
+	fmt.Printf("Resync %s with two consecutive codes\n", configData.SerialNumber)
+	code1, code2, err := promptConsecutiveCodes()
+	if err != nil {
+		return err
+	}
+	if err := aws.ResyncMFADevice(credProfile, config.clientConfigFor(profile), configData.SerialNumber, code1, code2); err != nil {
+		return awslogin.NewLoginError(profile, "failed to resync mfa device", err, false)
+	}
+	resetMFAFailures(profile)
+	fmt.Fprintln(infoOut(), a.Green("mfa device resynced, login again with the next code"))
+	return nil
+}
+
+// recordMFAFailure counts invalid mfa code of profile, the count is kept in err for its hint
+func recordMFAFailure(profile string, err error) {
+	var loginErr *LoginError
+	if !errors.As(err, &loginErr) || loginErr.Kind != awslogin.InvalidMFACode {
+		return
+	}
+	failures := make(map[string]int)
+	readCacheJSON(mfaFailuresFile, &failures)
+	failures[profile]++
+	loginErr.Failures = failures[profile]
+	writeCacheJSON(mfaFailuresFile, failures)
+}
+
+// resetMFAFailures clears count of invalid mfa code after a successful login or resync
+func resetMFAFailures(profile string) {
+	failures := make(map[string]int)
+	readCacheJSON(mfaFailuresFile, &failures)
+	if _, ok := failures[profile]; !ok {
+		return
+	}
+	delete(failures, profile)
+	writeCacheJSON(mfaFailuresFile, failures)
+}
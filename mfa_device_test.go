@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/golang/mock/gomock"
+	"github.com/sixleaveakkm/aws-login/internal/awstest"
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/stretchr/testify/assert"
+	"rsc.io/qr"
+)
+
+func TestOtpauthURI(t *testing.T) {
+	uri := otpauthURI(&VirtualMFADevice{Seed: "ABCDEF", UserName: "alice", AccountID: "123456789012"})
+	assert.Equal(t, "otpauth://totp/Amazon%20Web%20Services:alice@123456789012?issuer=Amazon+Web+Services&secret=ABCDEF", uri)
+}
+
+func TestWriteQR(t *testing.T) {
+	code, err := qr.Encode("otpauth://totp/test?secret=ABCDEF", qr.M)
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	writeQR(&buf, code)
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	// two modules a line, with quiet zone of 2 modules around
+	assert.Len(t, lines, (code.Size+4+1)/2)
+	assert.Equal(t, strings.Repeat("█", code.Size+4), lines[0])
+}
+
+func TestRegisterMFADevice(t *testing.T) {
+	device := &VirtualMFADevice{SerialNumber: "arn:aws:iam::123456789012:mfa/dev", Seed: "ABCDEF", UserName: "alice", AccountID: "123456789012"}
+	codes := func() (string, string, error) { return "123456", "654321", nil }
+	tests := []struct {
+		name      string
+		enableErr error
+	}{
+		{"enabled", nil},
+		{"enable failed", awserr.New("InvalidAuthenticationCode", "Authentication code for the MFA device is not valid.", nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := awstest.WriteFolder(t, "[profile dev]\nregion = us-east-1\n",
+				"[dev]\naws_access_key_id = KEY\naws_secret_access_key = SECRET\n")
+			defer os.RemoveAll(dir)
+			_ = os.Remove(cacheFilePath(mfaCacheFile))
+			defer os.Remove(cacheFilePath(mfaCacheFile))
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			m := awslogin.NewMockAWS(ctrl)
+			aws = m
+			m.EXPECT().CreateVirtualMFADevice("dev", gomock.Any()).Return(device, nil)
+			m.EXPECT().EnableMFADevice("dev", gomock.Any(), device, "123456", "654321").Return(tt.enableErr)
+			if tt.enableErr != nil {
+				m.EXPECT().DeleteVirtualMFADevice("dev", gomock.Any(), device.SerialNumber).Return(nil)
+			}
+
+			var out bytes.Buffer
+			config := mustNewConfig(t, dir)
+			err := registerMFADevice(config, "dev", "", &out, codes)
+			assert.Contains(t, out.String(), "or enter the secret manually: ABCDEF")
+			configData, _ := config.LoadConfig("dev")
+			if tt.enableErr != nil {
+				assert.Error(t, err)
+				assert.Empty(t, configData.SerialNumber)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, device.SerialNumber, configData.SerialNumber)
+			assert.Equal(t, device.SerialNumber, readMFACache()["dev"].Serial)
+		})
+	}
+}
+
+func TestInvalidMFACodeSuggestsResync(t *testing.T) {
+	originalFolder := awsFoldPath
+	awsFoldPath = mfaFolder
+	defer func() { awsFoldPath = originalFolder }()
+	_ = os.Remove(cacheFilePath(mfaFailuresFile))
+	defer os.Remove(cacheFilePath(mfaFailuresFile))
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := awslogin.NewMockAWS(ctrl)
+	aws = m
+	invalidCode := awserr.New("AccessDenied", "MultiFactorAuthentication failed with invalid MFA one time pass code.", nil)
+	m.EXPECT().GetMFASession(gomock.Any()).Return(nil, invalidCode).Times(awslogin.MFAResyncThreshold)
+
+	var loginErr *LoginError
+	for i := 1; i <= awslogin.MFAResyncThreshold; i++ {
+		_, err := login("dev", "123456", false)
+		assert.True(t, errors.As(err, &loginErr))
+		assert.Equal(t, i, loginErr.Failures)
+	}
+	assert.Contains(t, loginErr.Hint(), "aws-login mfa resync -p dev")
+
+	resetMFAFailures("dev")
+	failures := make(map[string]int)
+	readCacheJSON(mfaFailuresFile, &failures)
+	assert.NotContains(t, failures, "dev")
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	Output     = "output"
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+var (
+	// a colors text printed, colors are disabled when stdout is not a terminal or NO_COLOR is set
+	a            = aurora.NewAurora(true)
+	outputFormat = OutputText
+)
+
+var outputFlag = &cli.StringFlag{
+	Name:    Output,
+	Aliases: []string{"o"},
+	Usage:   "output format, \"text\" or \"json\"",
+	Value:   OutputText,
+	EnvVars: []string{"AWS_LOGIN_OUTPUT"},
+}
+
+// LoginResult is printed after login succeeded
+type LoginResult struct {
+	Profile      string     `json:"profile"`
+	Kind         string     `json:"kind"`
+	Identity     string     `json:"identity"`
+	Expiration   *time.Time `json:"expiry,omitempty"`
+	FilesChanged []string   `json:"files_changed"`
+}
+
+// ConfigResult is printed after config succeeded
+type ConfigResult struct {
+	Profile      string   `json:"profile"`
+	Kind         string   `json:"kind"`
+	FilesChanged []string `json:"files_changed"`
+}
+
+// ErrorResult is printed when command failed in json mode
+type ErrorResult struct {
+	Error ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	Kind     string `json:"kind"`
+	Message  string `json:"message"`
+	Hint     string `json:"hint,omitempty"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// loadOutputOptions sets output format and colors from global flags
+func loadOutputOptions(c *cli.Context) error {
+	switch format := c.String(Output); format {
+	case OutputText, OutputJSON:
+		outputFormat = format
+	default:
+		return fmt.Errorf("output must be %q or %q, got %q", OutputText, OutputJSON, format)
+	}
+	_, noColor := os.LookupEnv("NO_COLOR")
+	a = aurora.NewAurora(!noColor && isTerminal(os.Stdout))
+	return nil
+}
+
+// isTerminal reports whether f is a character device
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// isInteractive reports whether aws-login could prompt user for input.
+// Prompts are suppressed in json mode or when stdin or stdout is not a terminal.
+func isInteractive() bool {
+	return outputFormat == OutputText && isTerminal(os.Stdin) && isTerminal(os.Stdout)
+}
+
+// infoOut is where informational messages go, stdout is kept for results in json mode
+func infoOut() io.Writer {
+	if outputFormat == OutputJSON {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// printJSON prints v as indented json to stdout
+func printJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// printResult prints result of a command in json mode, text mode prints nothing
+func printResult(v interface{}) {
+	if outputFormat == OutputJSON {
+		printJSON(v)
+	}
+}
+
+// newLoginResult creates result of login, identity is mfa serial or role arn
+func newLoginResult(config *Config, profile string, kind string, identity string, cred *SessionCredential) *LoginResult {
+	result := &LoginResult{
+		Profile:      profile,
+		Kind:         kind,
+		Identity:     identity,
+		FilesChanged: config.ChangedFiles(),
+	}
+	if !cred.Expiration.IsZero() {
+		result.Expiration = &cred.Expiration
+	}
+	return result
+}
+
+// newErrorResult converts err to structured error
+func newErrorResult(err error) *ErrorResult {
+	detail := ErrorDetail{
+		Kind:     awslogin.UnknownError.String(),
+		Message:  err.Error(),
+		ExitCode: exitCode(err),
+	}
+	var loginErr *LoginError
+	if errors.As(err, &loginErr) {
+		detail.Kind = loginErr.Kind.String()
+		detail.Hint = loginErr.Hint()
+	} else if errors.Is(err, awslogin.ErrProfileNotFound) {
+		detail.Kind = awslogin.ProfileNotFound.String()
+	}
+	return &ErrorResult{Error: detail}
+}
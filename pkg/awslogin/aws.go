@@ -0,0 +1,411 @@
+package awslogin
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	aws_ "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	MFAPrefix = "arn:aws:iam::"
+)
+
+// AccountIDFromArn gets account id from arn like "arn:aws:iam::123456789012:mfa/user", empty if not an arn
+func AccountIDFromArn(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 || parts[0] != "arn" {
+		return ""
+	}
+	return parts[4]
+}
+
+type GetMFASessionInput struct {
+	// Profile name without mfa
+	Profile         string
+	SerialNumber    string
+	DurationSeconds int64
+	Code            string
+	Client          ClientConfig
+}
+
+type GetAssumeRoleRoleInput struct {
+	// SourceProfile name of original profile name
+	SourceProfile string
+	// CredentialSource is used instead of SourceProfile if not empty, one of CredentialSources
+	CredentialSource string
+	AssumeRoleArn    string
+	SerialNumber     string
+	DurationSeconds  int64
+	Code             string
+	Client           ClientConfig
+}
+
+// MFADevice is a mfa device assigned to an iam user
+type MFADevice struct {
+	SerialNumber string
+	EnableDate   time.Time
+	Virtual      bool
+}
+
+// VirtualMFADevice is a virtual mfa device created for an iam user, not enabled yet
+type VirtualMFADevice struct {
+	SerialNumber string
+	// Seed is the base32 secret of the device
+	Seed      string
+	UserName  string
+	AccountID string
+}
+
+// CallerIdentity is who the credential of a profile is
+type CallerIdentity struct {
+	Account string
+	Arn     string
+	UserID  string
+	// Alias is empty if account has no alias or it could not be listed
+	Alias string
+}
+
+// EKSCluster is where an eks cluster serves and the ca it is signed by
+type EKSCluster struct {
+	Name     string
+	Arn      string
+	Endpoint string
+	// CertificateAuthority is base64 encoded pem of the cluster ca
+	CertificateAuthority string
+}
+
+// ECRAuthorization is the docker login of ecr registries in region of client
+type ECRAuthorization struct {
+	Username  string
+	Password  string
+	ExpiresAt time.Time
+}
+
+type AWS interface {
+	// GetMFAString get mfa string with 1.5 seconds timeout.
+	// GetMFAString is only used for completion.
+	//
+	// It get mfa information by calling aws api of this session,
+	// which requires permission `iam:ListMFADevices` to
+	// at least Resource `arn:aws:iam::*:user/${aws:username}` (your own user)
+	// with timeout 1.5 second, if timeout, the request is canceled and it returns fix prefix "arn:aws:iam::"
+	GetMFAString(profile string, client ClientConfig) string
+
+	// ListMFADevices lists mfa devices of the user of profile, which could give codes.
+	// Virtual devices assigned to the user are also looked up by ListVirtualMFADevices,
+	// which requires `iam:ListVirtualMFADevices` and is skipped without it.
+	ListMFADevices(profile string, client ClientConfig) ([]MFADevice, error)
+
+	// CreateVirtualMFADevice creates a virtual mfa device named after the user of profile
+	CreateVirtualMFADevice(profile string, client ClientConfig) (*VirtualMFADevice, error)
+	// EnableMFADevice assigns device to user with two consecutive codes
+	EnableMFADevice(profile string, client ClientConfig, device *VirtualMFADevice, code1 string, code2 string) error
+	// DeleteVirtualMFADevice deletes a device not enabled, used when enabling failed
+	DeleteVirtualMFADevice(profile string, client ClientConfig, serial string) error
+
+	// ResyncMFADevice resyncs device of the user of profile with two consecutive codes
+	ResyncMFADevice(profile string, client ClientConfig, serial string, code1 string, code2 string) error
+
+	// GetCallerIdentity calls sts GetCallerIdentity with credential of profile,
+	// account alias is also listed with withAlias, which requires `iam:ListAccountAliases`.
+	GetCallerIdentity(profile string, client ClientConfig, withAlias bool) (*CallerIdentity, error)
+
+	// DescribeEKSCluster gets endpoint and ca of eks cluster with credential of profile,
+	// which requires `eks:DescribeCluster`.
+	DescribeEKSCluster(profile string, client ClientConfig, name string) (*EKSCluster, error)
+
+	// GetECRAuthorizationToken gets docker login of ecr registries with credential of profile,
+	// which requires `ecr:GetAuthorizationToken`.
+	GetECRAuthorizationToken(profile string, client ClientConfig) (*ECRAuthorization, error)
+
+	GetMFASession(input *GetMFASessionInput) (*SessionCredential, error)
+	GetAssumeRoleSession(input *GetAssumeRoleRoleInput) (*SessionCredential, error)
+}
+
+type AWSImpl struct {
+}
+
+func (s AWSImpl) GetMFAString(profile string, client ClientConfig) string {
+	sess, err := NewSession(profile, client)
+	if err != nil {
+		return MFAPrefix
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	res, err := NewIAMClient(sess, client).ListMFADevicesWithContext(ctx, &iam.ListMFADevicesInput{})
+	if err != nil || len(res.MFADevices) == 0 || res.MFADevices[0].SerialNumber == nil {
+		return MFAPrefix
+	}
+	return *res.MFADevices[0].SerialNumber
+}
+
+func (s AWSImpl) ListMFADevices(profile string, client ClientConfig) ([]MFADevice, error) {
+	sess, err := NewSession(profile, client)
+	if err != nil {
+		return nil, err
+	}
+	svc := NewIAMClient(sess, client)
+
+	devices := make([]MFADevice, 0)
+	index := make(map[string]int)
+	add := func(serial string, enableDate time.Time, virtual bool) {
+		// security keys (u2f) could not give codes
+		if strings.Contains(serial, ":u2f/") {
+			return
+		}
+		if i, ok := index[serial]; ok {
+			devices[i].Virtual = devices[i].Virtual || virtual
+			return
+		}
+		index[serial] = len(devices)
+		devices = append(devices, MFADevice{SerialNumber: serial, EnableDate: enableDate, Virtual: virtual})
+	}
+
+	err = svc.ListMFADevicesPages(&iam.ListMFADevicesInput{}, func(page *iam.ListMFADevicesOutput, _ bool) bool {
+		for _, d := range page.MFADevices {
+			serial := aws_.StringValue(d.SerialNumber)
+			add(serial, aws_.TimeValue(d.EnableDate), strings.Contains(serial, ":mfa/"))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// virtual devices are listed for whole account, only ones assigned to this user are taken
+	user, err := svc.GetUser(&iam.GetUserInput{})
+	if err != nil {
+		client.logf("skip listing virtual mfa devices, %v", err)
+		return devices, nil
+	}
+	err = svc.ListVirtualMFADevicesPages(&iam.ListVirtualMFADevicesInput{
+		AssignmentStatus: aws_.String(iam.AssignmentStatusTypeAssigned),
+	}, func(page *iam.ListVirtualMFADevicesOutput, _ bool) bool {
+		for _, d := range page.VirtualMFADevices {
+			if d.User == nil || aws_.StringValue(d.User.Arn) != aws_.StringValue(user.User.Arn) {
+				continue
+			}
+			add(aws_.StringValue(d.SerialNumber), aws_.TimeValue(d.EnableDate), true)
+		}
+		return true
+	})
+	if err != nil {
+		client.logf("skip listing virtual mfa devices, %v", err)
+	}
+	return devices, nil
+}
+
+func (s AWSImpl) CreateVirtualMFADevice(profile string, client ClientConfig) (*VirtualMFADevice, error) {
+	sess, err := NewSession(profile, client)
+	if err != nil {
+		return nil, err
+	}
+	svc := NewIAMClient(sess, client)
+	user, err := svc.GetUser(&iam.GetUserInput{})
+	if err != nil {
+		return nil, err
+	}
+	userName := aws_.StringValue(user.User.UserName)
+	output, err := svc.CreateVirtualMFADevice(&iam.CreateVirtualMFADeviceInput{
+		VirtualMFADeviceName: aws_.String(userName),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &VirtualMFADevice{
+		SerialNumber: aws_.StringValue(output.VirtualMFADevice.SerialNumber),
+		Seed:         string(output.VirtualMFADevice.Base32StringSeed),
+		UserName:     userName,
+		AccountID:    AccountIDFromArn(aws_.StringValue(user.User.Arn)),
+	}, nil
+}
+
+func (s AWSImpl) EnableMFADevice(profile string, client ClientConfig, device *VirtualMFADevice, code1 string, code2 string) error {
+	sess, err := NewSession(profile, client)
+	if err != nil {
+		return err
+	}
+	_, err = NewIAMClient(sess, client).EnableMFADevice(&iam.EnableMFADeviceInput{
+		UserName:            aws_.String(device.UserName),
+		SerialNumber:        aws_.String(device.SerialNumber),
+		AuthenticationCode1: aws_.String(code1),
+		AuthenticationCode2: aws_.String(code2),
+	})
+	return err
+}
+
+func (s AWSImpl) DeleteVirtualMFADevice(profile string, client ClientConfig, serial string) error {
+	sess, err := NewSession(profile, client)
+	if err != nil {
+		return err
+	}
+	_, err = NewIAMClient(sess, client).DeleteVirtualMFADevice(&iam.DeleteVirtualMFADeviceInput{
+		SerialNumber: aws_.String(serial),
+	})
+	return err
+}
+
+func (s AWSImpl) ResyncMFADevice(profile string, client ClientConfig, serial string, code1 string, code2 string) error {
+	sess, err := NewSession(profile, client)
+	if err != nil {
+		return err
+	}
+	svc := NewIAMClient(sess, client)
+	user, err := svc.GetUser(&iam.GetUserInput{})
+	if err != nil {
+		return err
+	}
+	_, err = svc.ResyncMFADevice(&iam.ResyncMFADeviceInput{
+		UserName:            user.User.UserName,
+		SerialNumber:        aws_.String(serial),
+		AuthenticationCode1: aws_.String(code1),
+		AuthenticationCode2: aws_.String(code2),
+	})
+	return err
+}
+
+func (s AWSImpl) GetCallerIdentity(profile string, client ClientConfig, withAlias bool) (*CallerIdentity, error) {
+	sess, err := NewSession(profile, client)
+	if err != nil {
+		return nil, err
+	}
+	output, err := NewSTSClient(sess, client).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, err
+	}
+	identity := &CallerIdentity{
+		Account: aws_.StringValue(output.Account),
+		Arn:     aws_.StringValue(output.Arn),
+		UserID:  aws_.StringValue(output.UserId),
+	}
+	if !withAlias {
+		return identity, nil
+	}
+	aliases, err := NewIAMClient(sess, client).ListAccountAliases(&iam.ListAccountAliasesInput{})
+	if err != nil {
+		client.logf("skip account alias, %v", err)
+	} else if len(aliases.AccountAliases) > 0 {
+		identity.Alias = aws_.StringValue(aliases.AccountAliases[0])
+	}
+	return identity, nil
+}
+
+func (s AWSImpl) DescribeEKSCluster(profile string, client ClientConfig, name string) (*EKSCluster, error) {
+	sess, err := NewSession(profile, client)
+	if err != nil {
+		return nil, err
+	}
+	output, err := eks.New(sess).DescribeCluster(&eks.DescribeClusterInput{Name: aws_.String(name)})
+	if err != nil {
+		return nil, err
+	}
+	cluster := &EKSCluster{
+		Name:     aws_.StringValue(output.Cluster.Name),
+		Arn:      aws_.StringValue(output.Cluster.Arn),
+		Endpoint: aws_.StringValue(output.Cluster.Endpoint),
+	}
+	if output.Cluster.CertificateAuthority != nil {
+		cluster.CertificateAuthority = aws_.StringValue(output.Cluster.CertificateAuthority.Data)
+	}
+	return cluster, nil
+}
+
+func (s AWSImpl) GetECRAuthorizationToken(profile string, client ClientConfig) (*ECRAuthorization, error) {
+	sess, err := NewSession(profile, client)
+	if err != nil {
+		return nil, err
+	}
+	output, err := ecr.New(sess).GetAuthorizationToken(&ecr.GetAuthorizationTokenInput{})
+	if err != nil {
+		return nil, err
+	}
+	if len(output.AuthorizationData) == 0 {
+		return nil, fmt.Errorf("no authorization data returned by ecr")
+	}
+	data := output.AuthorizationData[0]
+	// token is base64 of "<username>:<password>"
+	token, err := base64.StdEncoding.DecodeString(aws_.StringValue(data.AuthorizationToken))
+	if err != nil {
+		return nil, fmt.Errorf("invalid authorization token, %w", err)
+	}
+	parts := strings.SplitN(string(token), ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid authorization token")
+	}
+	return &ECRAuthorization{
+		Username:  parts[0],
+		Password:  parts[1],
+		ExpiresAt: aws_.TimeValue(data.ExpiresAt),
+	}, nil
+}
+
+func (s AWSImpl) GetMFASession(input *GetMFASessionInput) (*SessionCredential, error) {
+	sess, err := NewSession(input.Profile, input.Client)
+	if err != nil {
+		return nil, err
+	}
+	svc := NewSTSClient(sess, input.Client)
+
+	output, err := svc.GetSessionToken(&sts.GetSessionTokenInput{
+		DurationSeconds: aws_.Int64(input.DurationSeconds),
+		SerialNumber:    aws_.String(input.SerialNumber),
+		TokenCode:       aws_.String(input.Code),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &SessionCredential{
+		AccessKey:    *output.Credentials.AccessKeyId,
+		SecretKey:    *output.Credentials.SecretAccessKey,
+		SessionToken: *output.Credentials.SessionToken,
+		Expiration:   aws_.TimeValue(output.Credentials.Expiration),
+	}, nil
+}
+
+func (s AWSImpl) GetAssumeRoleSession(input *GetAssumeRoleRoleInput) (*SessionCredential, error) {
+	var sess *session.Session
+	var err error
+	if input.CredentialSource != "" {
+		sess, err = NewSourceSession(input.CredentialSource, input.Client)
+	} else {
+		sess, err = NewSession(input.SourceProfile, input.Client)
+	}
+	if err != nil {
+		return nil, err
+	}
+	svc := NewSTSClient(sess, input.Client)
+
+	assumeRoleInput := &sts.AssumeRoleInput{
+		RoleArn: &input.AssumeRoleArn,
+		// Give a dummy session name
+		RoleSessionName: aws_.String("cli"),
+	}
+	// zero duration uses the default of the role, sts rejects durations under 900
+	if input.DurationSeconds > 0 {
+		assumeRoleInput.DurationSeconds = aws_.Int64(input.DurationSeconds)
+	}
+	// roles without mfa, e.g. refreshed by agent or assumed from credential source, send no serial
+	if input.SerialNumber != "" {
+		assumeRoleInput.SerialNumber = &input.SerialNumber
+		assumeRoleInput.TokenCode = &input.Code
+	}
+	output, err := svc.AssumeRole(assumeRoleInput)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionCredential{
+		AccessKey:    *output.Credentials.AccessKeyId,
+		SecretKey:    *output.Credentials.SecretAccessKey,
+		SessionToken: *output.Credentials.SessionToken,
+		Expiration:   aws_.TimeValue(output.Credentials.Expiration),
+	}, nil
+}
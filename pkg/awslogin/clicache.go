@@ -0,0 +1,186 @@
+package awslogin
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// CLICacheFolder is the folder aws cli caches sessions in, relative to aws folder
+const CLICacheFolder = "cli/cache"
+
+// Executable gets absolute path of the running aws-login, so tools running it don't depend on PATH.
+// The bare name is used if the path could not be found.
+func Executable() string {
+	path, err := os.Executable()
+	if err != nil {
+		return "aws-login"
+	}
+	return path
+}
+
+// CredentialProcess is set as credential_process of cache-only profiles, so sdks get sessions of them from the cache.
+// The command is run by a shell, path with spaces is quoted.
+func CredentialProcess(profile string) string {
+	command := Executable()
+	if strings.ContainsAny(command, " \t") {
+		command = `"` + command + `"`
+	}
+	return command + " credential-process --profile " + profile
+}
+
+// CLICacheEntry is a session cached in the same layout as aws cli does
+type CLICacheEntry struct {
+	Credentials struct {
+		AccessKeyId     string
+		SecretAccessKey string
+		SessionToken    string
+		Expiration      time.Time
+	}
+	ProviderType string `json:",omitempty"`
+}
+
+// CLICacheKey is the file name of session in cli cache, sha1 of what the session is like aws cli does.
+// Profiles with the same role, source and mfa device share the session.
+func CLICacheKey(conf *ConfigData) string {
+	args, _ := json.Marshal(map[string]string{
+		"RoleArn":       conf.AssumeRoleArn,
+		"SourceProfile": conf.SourceProfile,
+		"Source":        conf.CredentialSource,
+		"SerialNumber":  conf.SerialNumber,
+	})
+	sum := sha1.Sum(args)
+	return hex.EncodeToString(sum[:])
+}
+
+// CLICachePath gets path of session of profile config in cli cache
+func (c *Config) CLICachePath(conf *ConfigData) string {
+	return c.PathOf(filepath.Join(filepath.FromSlash(CLICacheFolder), CLICacheKey(conf)+".json"))
+}
+
+// LoadCachedSession reads session of profile config from cli cache
+func (c *Config) LoadCachedSession(conf *ConfigData) (*SessionCredential, error) {
+	path := c.CLICachePath(conf)
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry CLICacheEntry
+	if err := json.Unmarshal(content, &entry); err != nil {
+		return nil, fmt.Errorf("failed to read %s, %w", path, err)
+	}
+	return &SessionCredential{
+		AccessKey:    entry.Credentials.AccessKeyId,
+		SecretKey:    entry.Credentials.SecretAccessKey,
+		SessionToken: entry.Credentials.SessionToken,
+		Expiration:   entry.Credentials.Expiration,
+	}, nil
+}
+
+// SaveCachedSession writes session of profile config to cli cache with permission 0600.
+// In dry run mode, nothing is written.
+func (c *Config) SaveCachedSession(conf *ConfigData, cred *SessionCredential) error {
+	var entry CLICacheEntry
+	entry.Credentials.AccessKeyId = cred.AccessKey
+	entry.Credentials.SecretAccessKey = cred.SecretKey
+	entry.Credentials.SessionToken = cred.SessionToken
+	entry.Credentials.Expiration = cred.Expiration.UTC()
+	entry.ProviderType = "aws-login"
+	content, err := json.Marshal(&entry)
+	if err != nil {
+		return err
+	}
+
+	path := c.CLICachePath(conf)
+	c.markChanged(path)
+	if c.DryRun {
+		c.logf("dry run, not saving %s", path)
+		return nil
+	}
+	c.logf("saving %s", path)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	// written aside and renamed, so readers never see a partial file
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".aws-login-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSession reads session of profile, from cli cache for cache-only profiles and from credentials for others.
+// The session may be expired.
+func (c *Config) LoadSession(profile string) (*SessionCredential, error) {
+	if conf, err := c.ProfileConfig(profile); err == nil && conf.CacheOnly {
+		return c.LoadCachedSession(conf)
+	}
+	section, err := c.Cred.GetSection(profile)
+	if err != nil {
+		return nil, fmt.Errorf("%q credential %w", profile, ErrProfileNotFound)
+	}
+	var cred SessionCredential
+	err = section.MapTo(&cred)
+	return &cred, err
+}
+
+// StoreSession saves session of logged in profile.
+// With cacheOnly, or c_cache_only of the profile, it is saved to cli cache only:
+// the profile gets credential_process reading the cache, and keys are removed from its section of credentials,
+// so sdks find the session without temporary keys in credentials file.
+func (c *Config) StoreSession(profile string, conf *ConfigData, cred *SessionCredential, cacheOnly bool) error {
+	if !cacheOnly && !conf.CacheOnly {
+		return c.SaveCredentialLocked(cred, profile)
+	}
+	if err := c.SaveCachedSession(conf, cred); err != nil {
+		return err
+	}
+	return c.wireCachedSession(profile, profile, conf)
+}
+
+// wireCachedSession makes sdks read session of profile from cli cache when profile name is used.
+// name is the profile to wire, which is profile itself or "default".
+// Default only gets what defaultSection sets, it reads the session of profile.
+func (c *Config) wireCachedSession(name string, profile string, conf *ConfigData) error {
+	var section *ini.Section
+	var err error
+	if name == "default" {
+		section, err = c.defaultSection(conf)
+	} else {
+		wired := *conf
+		wired.CacheOnly = true
+		section = c.Conf.Section("profile " + name)
+		err = section.ReflectFrom(&wired)
+	}
+	if err != nil {
+		return err
+	}
+	section.Key("credential_process").SetValue(CredentialProcess(profile))
+	if err := c.Save(c.Conf, ConfigFile); err != nil {
+		return err
+	}
+	// keys in credentials take priority over credential_process
+	return c.updateCredentialLocked(name, func(section *ini.Section) error {
+		for _, key := range CredentialKeys {
+			section.DeleteKey(key)
+		}
+		section.DeleteKey("aws_expiration")
+		return nil
+	})
+}
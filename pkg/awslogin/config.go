@@ -0,0 +1,395 @@
+// Package awslogin reads and writes aws config and credentials files the way aws-login does,
+// and logs in mfa and role profiles configured by it.
+//
+// It has no global state, the aws folder, aws client and client settings are given explicitly,
+// and failures are returned as errors.
+package awslogin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	. "github.com/deckarep/golang-set"
+	"gopkg.in/ini.v1"
+)
+
+const (
+	// ConfigFile and CredentialsFile are names of files in aws folder
+	ConfigFile      = "config"
+	CredentialsFile = "credentials"
+
+	// NoMFASuffix is appended to profile name for the section keeping long-term keys of a mfa profile
+	NoMFASuffix = "_no_mfa"
+)
+
+// ErrProfileNotFound is returned when no section of profile exists
+var ErrProfileNotFound = errors.New("profile not found")
+
+// CredentialKeys are keys of a credential in credentials file
+var CredentialKeys = []string{"aws_access_key_id", "aws_secret_access_key", "aws_session_token"}
+
+type SessionCredential struct {
+	AccessKey    string    `ini:"aws_access_key_id,omitempty"`
+	SecretKey    string    `ini:"aws_secret_access_key,omitempty"`
+	SessionToken string    `ini:"aws_session_token,omitempty"`
+	Expiration   time.Time `ini:"aws_expiration,omitempty"`
+}
+
+type ConfigData struct {
+	Region string `ini:"region,omitempty"`
+	Output string `ini:"output,omitempty"`
+
+	SerialNumber    string `ini:"mfa_serial,omitempty"`
+	DurationSeconds int64  `ini:"duration,omitempty"`
+	SourceProfile   string `ini:"c_source_profile,omitempty"`
+	AssumeRoleArn   string `ini:"c_role_arn,omitempty"`
+	// CredentialSource is where source credentials of role come from instead of source profile,
+	// the standard `credential_source` key of aws cli
+	CredentialSource string `ini:"credential_source,omitempty"`
+
+	STSEndpoint          string `ini:"c_sts_endpoint_url,omitempty"`
+	IAMEndpoint          string `ini:"c_iam_endpoint_url,omitempty"`
+	STSRegionalEndpoints string `ini:"sts_regional_endpoints,omitempty"`
+	// UseFIPSEndpoint is nil if not set in profile, false in profile turns off global setting
+	UseFIPSEndpoint *bool  `ini:"use_fips_endpoint,omitempty"`
+	CABundle        string `ini:"ca_bundle,omitempty"`
+
+	// CacheOnly keeps sessions in cli cache instead of credentials file
+	CacheOnly bool `ini:"c_cache_only,omitempty"`
+
+	// DockerRegistries are ecr registry hosts docker logs in with this profile, separated by comma
+	DockerRegistries string `ini:"c_docker_registries,omitempty"`
+
+	PreLoginHook  string `ini:"c_pre_login_hook,omitempty"`
+	PostLoginHook string `ini:"c_post_login_hook,omitempty"`
+	HookTimeout   int64  `ini:"c_hook_timeout,omitempty"`
+	HookSecrets   bool   `ini:"c_hook_secrets,omitempty"`
+	HookStrict    bool   `ini:"c_hook_strict,omitempty"`
+}
+
+// IsRole checks profile assumes a role, with a source profile or a credential source
+func (d *ConfigData) IsRole() bool {
+	return d.SourceProfile != "" || d.CredentialSource != ""
+}
+
+// Options changes how Config saves files
+type Options struct {
+	// OutputFolder is where files are saved instead of the folder they are loaded from, used by tests
+	OutputFolder string
+	// DryRun keeps changes in memory, contents of loaded files are recorded to diff with
+	DryRun bool
+	// Logf receives debug logs, nothing is logged if nil
+	Logf func(format string, args ...interface{})
+}
+
+type Config struct {
+	Conf *ini.File
+	Cred *ini.File
+
+	// Folder is the aws folder files are loaded from
+	Folder string
+	Options
+
+	// changed records paths of files saved
+	changed map[string]bool
+	// original contents of files, only recorded in dry run mode
+	original map[string]string
+}
+
+// NewConfig loads config and credentials files in folder
+func NewConfig(folder string, opts Options) (*Config, error) {
+	c := &Config{Folder: folder, Options: opts}
+	c.logf("loading %s and %s", filepath.Join(folder, ConfigFile), filepath.Join(folder, CredentialsFile))
+	cfg, err := ini.LoadSources(ini.LoadOptions{
+		SkipUnrecognizableLines: true,
+	}, filepath.Join(folder, ConfigFile))
+	if err != nil {
+		return nil, err
+	}
+	c.Conf = cfg
+
+	cred, err := ini.LoadSources(ini.LoadOptions{
+		SkipUnrecognizableLines: true,
+	}, filepath.Join(folder, CredentialsFile))
+	if err != nil {
+		return nil, err
+	}
+	c.Cred = cred
+
+	if opts.DryRun {
+		c.original = map[string]string{
+			ConfigFile:      Render(c.Conf),
+			CredentialsFile: Render(c.Cred),
+		}
+	}
+	return c, nil
+}
+
+func (c *Config) logf(format string, args ...interface{}) {
+	if c.Logf != nil {
+		c.Logf(format, args...)
+	}
+}
+
+// Render renders f as it will be saved
+func Render(f *ini.File) string {
+	var buf bytes.Buffer
+	_, _ = f.WriteTo(&buf)
+	return buf.String()
+}
+
+// ShortSectionName get profile name without "profile " prefix.
+func ShortSectionName(name string) string {
+	s := strings.Split(name, "profile ")
+	return s[len(s)-1]
+}
+
+// ListPossibleProfiles list possible profiles to config mfa.
+// Exclude profiles with suffix "_no_mfa"
+func (c *Config) ListPossibleProfiles() Set {
+	profiles := NewSet()
+	var sectionList = c.Conf.SectionStrings()
+	sectionList = append(sectionList, c.Cred.SectionStrings()...)
+	for _, profile := range sectionList {
+		if strings.HasSuffix(profile, NoMFASuffix) || profile == ini.DefaultSection {
+			continue
+		}
+		profiles.Add(ShortSectionName(profile))
+	}
+	return profiles
+}
+
+// BackupNoMFACredential get current credential and save to "_no_mfa".
+// no mfa profile matches original profile name
+func (c *Config) BackupNoMFACredential(profile string) error {
+	var credData SessionCredential
+	section, err := c.GetNoMFACredential(profile)
+	if err != nil {
+		return nil
+	}
+	_ = section.MapTo(&credData)
+	if credData.SessionToken == "" {
+		name := fmt.Sprintf("%s%s", profile, NoMFASuffix)
+		return c.SaveCredential(&credData, name)
+	}
+	return nil
+}
+
+// GetNoMFACredential get credential profile is not mfa
+func (c *Config) GetNoMFACredential(profile string) (*ini.Section, error) {
+	list := []string{
+		fmt.Sprintf("%s%s", profile, NoMFASuffix),
+		profile,
+	}
+	for i := 0; i < len(list); i++ {
+		section, err := c.Cred.GetSection(list[i])
+		if err == nil {
+			return section, nil
+		}
+	}
+	return nil, ErrProfileNotFound
+}
+
+// ProfileConfig reads section "profile <profile>" of config, without fallback to other sections
+func (c *Config) ProfileConfig(profile string) (*ConfigData, error) {
+	section, err := c.Conf.GetSection("profile " + profile)
+	if err != nil {
+		return nil, fmt.Errorf("%q %w", profile, ErrProfileNotFound)
+	}
+	var conf ConfigData
+	err = section.MapTo(&conf)
+	return &conf, err
+}
+
+// LoadConfig reads config of profile with fallback, same as LoadSection
+func (c *Config) LoadConfig(profile string) (*ConfigData, error) {
+	section, err := c.LoadSection(profile, c.Conf)
+	if err != nil {
+		return nil, err
+	}
+	var conf ConfigData
+	err = section.MapTo(&conf)
+	return &conf, err
+}
+
+// SaveConfig saves conf to section "profile <profile>" of config
+func (c *Config) SaveConfig(conf *ConfigData, profile string) error {
+	if err := c.Conf.Section("profile " + profile).ReflectFrom(conf); err != nil {
+		return err
+	}
+	return c.Save(c.Conf, ConfigFile)
+}
+
+// defaultSection sets region, output and mfa_serial of conf to section of default profile, the same as aws cli sets.
+// Other settings of aws-login, and credential_process of an earlier cache-only login, are removed,
+// they belong to the profile logged in, not to default.
+func (c *Config) defaultSection(conf *ConfigData) (*ini.Section, error) {
+	section := c.Conf.Section("profile default")
+	section.DeleteKey("credential_process")
+	for _, key := range configDataKeys() {
+		if key != "region" && key != "output" {
+			section.DeleteKey(key)
+		}
+	}
+	err := section.ReflectFrom(&ConfigData{Region: conf.Region, Output: conf.Output, SerialNumber: conf.SerialNumber})
+	return section, err
+}
+
+// SaveDefaultConfig saves conf of profile logged in with default, see defaultSection
+func (c *Config) SaveDefaultConfig(conf *ConfigData) error {
+	if _, err := c.defaultSection(conf); err != nil {
+		return err
+	}
+	return c.Save(c.Conf, ConfigFile)
+}
+
+// configDataKeys gets keys of ConfigData in config file
+func configDataKeys() []string {
+	t := reflect.TypeOf(ConfigData{})
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if name := strings.Split(t.Field(i).Tag.Get("ini"), ",")[0]; name != "" && name != "-" {
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
+
+// LoadSection loads profile file in order
+// <profile> is the name of profile.
+// "<profile>_no_mfa" => "profile <profile>_no_mfa" => "<profile>" => "profile <profile>"
+// return the first one found, if none exists, return ErrProfileNotFound
+func (c *Config) LoadSection(profile string, from *ini.File) (*ini.Section, error) {
+	list := []string{
+		fmt.Sprintf("%s%s", profile, NoMFASuffix),
+		fmt.Sprintf("profile %s%s", profile, NoMFASuffix),
+		profile,
+		fmt.Sprintf("profile %s", profile),
+	}
+	for i := 0; i < len(list); i++ {
+		section, err := from.GetSection(list[i])
+		if err == nil {
+			c.logf("profile %q resolved to section [%s]", profile, list[i])
+			return section, nil
+		}
+	}
+	c.logf("profile %q not found, tried sections %q", profile, list)
+	return nil, ErrProfileNotFound
+}
+
+// LoadCredential read credentialData from profile with fallback
+// <profile> is the name of profile.
+// "<profile>_no_mfa" => "profile <profile>_no_mfa" => "<profile>" => "profile <profile>"
+// return the first one found, if none exists, return ErrProfileNotFound
+func (c *Config) LoadCredential(profile string) (*SessionCredential, error) {
+	section, err := c.LoadSection(profile, c.Cred)
+	if err != nil {
+		return nil, err
+	}
+	var cred SessionCredential
+	err = section.MapTo(&cred)
+	return &cred, err
+}
+
+// SaveCredential saves cred to section <profile> of credentials
+func (c *Config) SaveCredential(cred *SessionCredential, profile string) error {
+	if err := c.Cred.Section(profile).ReflectFrom(cred); err != nil {
+		return fmt.Errorf("failed to save credential, %w", err)
+	}
+	return c.Save(c.Cred, CredentialsFile)
+}
+
+// SaveCredentialLocked saves cred of profile while holding lock of credentials file.
+// The file is loaded again under the lock and only the section of profile is replaced,
+// so sections saved meanwhile by other processes, e.g. agent and login, are kept.
+func (c *Config) SaveCredentialLocked(cred *SessionCredential, profile string) error {
+	return c.updateCredentialLocked(profile, func(section *ini.Section) error {
+		// keys not in cred, e.g. expiration of last session, are not left over
+		for _, key := range CredentialKeys {
+			section.DeleteKey(key)
+		}
+		section.DeleteKey("aws_expiration")
+		return section.ReflectFrom(cred)
+	})
+}
+
+// updateCredentialLocked updates section of profile in credentials with update while holding lock of the file
+func (c *Config) updateCredentialLocked(profile string, update func(section *ini.Section) error) error {
+	path := c.PathOf(CredentialsFile)
+	if !c.DryRun {
+		unlock, err := LockFile(path + ".lock")
+		if err != nil {
+			return fmt.Errorf("failed to lock %s, %w", path, err)
+		}
+		defer unlock()
+
+		fresh, err := ini.LoadSources(ini.LoadOptions{
+			SkipUnrecognizableLines: true,
+			Loose:                   true,
+		}, path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s, %w", path, err)
+		}
+		c.Cred = fresh
+	}
+	if err := update(c.Cred.Section(profile)); err != nil {
+		return err
+	}
+	return c.Save(c.Cred, CredentialsFile)
+}
+
+// Save writes f to file in aws folder, and records the path as changed.
+// In dry run mode, f is kept in memory.
+func (c *Config) Save(f *ini.File, file string) error {
+	path := c.PathOf(file)
+	c.markChanged(path)
+	if c.DryRun {
+		c.logf("dry run, not saving %s", path)
+		return nil
+	}
+	c.logf("saving %s", path)
+	return f.SaveTo(path)
+}
+
+// markChanged records path as changed
+func (c *Config) markChanged(path string) {
+	if c.changed == nil {
+		c.changed = make(map[string]bool)
+	}
+	c.changed[path] = true
+}
+
+// PathOf gets path file is saved to
+func (c *Config) PathOf(file string) string {
+	folder := c.Folder
+	if c.OutputFolder != "" {
+		folder = c.OutputFolder
+	}
+	return filepath.Join(folder, file)
+}
+
+// Changed tells whether file is saved
+func (c *Config) Changed(file string) bool {
+	return c.changed[c.PathOf(file)]
+}
+
+// Original gets content of file when it was loaded, only recorded in dry run mode
+func (c *Config) Original(file string) string {
+	return c.original[file]
+}
+
+// ChangedFiles lists paths of files saved
+func (c *Config) ChangedFiles() []string {
+	files := make([]string, 0, len(c.changed))
+	for path := range c.changed {
+		files = append(files, path)
+	}
+	sort.Strings(files)
+	return files
+}
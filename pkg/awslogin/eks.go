@@ -0,0 +1,51 @@
+package awslogin
+
+import (
+	"encoding/base64"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	// EKSTokenPrefix is prepended to the presigned url in eks tokens
+	EKSTokenPrefix = "k8s-aws-v1."
+	// eksClusterHeader is signed with the request, so the token is only accepted by the cluster
+	eksClusterHeader = "x-k8s-aws-id"
+	// eksTokenLifetime is shorter than 15 minutes eks accepts a presigned url, so clients refresh it in time
+	eksTokenLifetime = 14 * time.Minute
+)
+
+// EKSToken is a bearer token authenticating to eks clusters as who cred is
+type EKSToken struct {
+	Token      string
+	Expiration time.Time
+}
+
+// NewEKSToken presigns sts GetCallerIdentity for cluster with cred, no request is sent.
+// The token expires with the session if it expires earlier.
+func NewEKSToken(cred *SessionCredential, cluster string, client ClientConfig) (*EKSToken, error) {
+	if client.Region == "" {
+		// global endpoint, the same as aws-iam-authenticator defaults to
+		client.Region = "us-east-1"
+	}
+	sess, err := NewCredentialSession(cred, client)
+	if err != nil {
+		return nil, err
+	}
+	req, _ := NewSTSClient(sess, client).GetCallerIdentityRequest(&sts.GetCallerIdentityInput{})
+	req.HTTPRequest.Header.Add(eksClusterHeader, cluster)
+	presigned, err := req.Presign(60 * time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	expiration := time.Now().Add(eksTokenLifetime)
+	if !cred.Expiration.IsZero() && cred.Expiration.Before(expiration) {
+		expiration = cred.Expiration
+	}
+	return &EKSToken{
+		Token:      EKSTokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(presigned)),
+		Expiration: expiration,
+	}, nil
+}
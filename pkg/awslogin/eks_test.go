@@ -0,0 +1,35 @@
+package awslogin
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEKSToken(t *testing.T) {
+	cred := &SessionCredential{AccessKey: "KEY", SecretKey: "SECRET", SessionToken: "TOKEN", Expiration: time.Now().Add(time.Hour)}
+	token, err := NewEKSToken(cred, "prod", ClientConfig{Region: "ap-northeast-1", STSRegionalEndpoints: "regional"})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token.Token, EKSTokenPrefix))
+	assert.WithinDuration(t, time.Now().Add(eksTokenLifetime), token.Expiration, time.Minute)
+
+	presigned, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token.Token, EKSTokenPrefix))
+	assert.NoError(t, err)
+	u, err := url.Parse(string(presigned))
+	assert.NoError(t, err)
+	assert.Equal(t, "sts.ap-northeast-1.amazonaws.com", u.Host)
+	query := u.Query()
+	assert.Equal(t, "GetCallerIdentity", query.Get("Action"))
+	assert.Equal(t, "host;x-k8s-aws-id", query.Get("X-Amz-SignedHeaders"))
+	assert.Equal(t, "TOKEN", query.Get("X-Amz-Security-Token"))
+
+	// token doesn't outlive the session
+	cred.Expiration = time.Now().Add(5 * time.Minute)
+	token, err = NewEKSToken(cred, "prod", ClientConfig{})
+	assert.NoError(t, err)
+	assert.Equal(t, cred.Expiration, token.Expiration)
+}
@@ -0,0 +1,149 @@
+package awslogin
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// MFAResyncThreshold is how many invalid codes in a row make resync suggested
+const MFAResyncThreshold = 3
+
+// ErrorKind classifies login failures, each kind has its own hint.
+type ErrorKind int
+
+const (
+	UnknownError ErrorKind = iota
+	ProfileNotFound
+	InvalidMFACode
+	RoleAccessDenied
+	InvalidCredential
+	NetworkFailure
+	HookFailed
+	ReusedMFACode
+	InvalidConfig
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ProfileNotFound:
+		return "profile_not_found"
+	case InvalidMFACode:
+		return "invalid_mfa_code"
+	case RoleAccessDenied:
+		return "role_access_denied"
+	case InvalidCredential:
+		return "invalid_credential"
+	case NetworkFailure:
+		return "network_failure"
+	case HookFailed:
+		return "hook_failed"
+	case ReusedMFACode:
+		return "reused_mfa_code"
+	case InvalidConfig:
+		return "invalid_config"
+	default:
+		return "unknown"
+	}
+}
+
+// LoginError is returned when login failed for a known reason.
+type LoginError struct {
+	Kind    ErrorKind
+	Profile string
+	// Source is the section of long-term keys used when it isn't the one of Profile, e.g. of source profile of role
+	Source string
+	Err    error
+	// Failures counts invalid mfa codes in a row of the profile, including this one
+	Failures int
+}
+
+func (e *LoginError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LoginError) Unwrap() error {
+	return e.Err
+}
+
+// Hint is a suggestion to the user to solve the error.
+func (e *LoginError) Hint() string {
+	switch e.Kind {
+	case ProfileNotFound:
+		return "create the profile with `aws-login config <mfa|role> ...`"
+	case InvalidMFACode:
+		if e.Failures >= MFAResyncThreshold {
+			return fmt.Sprintf("the code failed %d times in a row, the device may be out of sync, try `aws-login mfa resync -p %s`", e.Failures, e.Profile)
+		}
+		return "the code is wrong, wait for the next code and try again, check mfa_serial of the profile if it keeps failing"
+	case ReusedMFACode:
+		return "the code has already been used, wait for the next code and try again"
+	case RoleAccessDenied:
+		return "check the role arn, and that the trust policy of the role allows your user (with mfa if the role requires it)"
+	case InvalidCredential:
+		source := e.Source
+		if source == "" {
+			source = e.Profile
+		}
+		return fmt.Sprintf("long-term keys are expired or invalid, update them with `aws configure --profile %s%s`",
+			strings.TrimSuffix(ShortSectionName(source), NoMFASuffix), NoMFASuffix)
+	case NetworkFailure:
+		return "check your network, proxy (--https-proxy) and ca bundle (--ca-bundle) settings"
+	case HookFailed:
+		return "check the hook executable, its output is printed above"
+	case InvalidConfig:
+		return "check config and credentials files exist in the aws folder, are readable and are valid ini files"
+	default:
+		return ""
+	}
+}
+
+// NewLoginError classifies err returned by sts when logging in profile.
+// op is the operation performing, e.g. "failed get mfa".
+func NewLoginError(profile string, op string, err error, assumeRole bool) error {
+	return &LoginError{
+		Kind:    ClassifyError(err, assumeRole),
+		Profile: profile,
+		Err:     fmt.Errorf("%s, %w", op, err),
+	}
+}
+
+// ClassifyError gets ErrorKind from sdk error.
+func ClassifyError(err error, assumeRole bool) ErrorKind {
+	if errors.Is(err, ErrProfileNotFound) {
+		return ProfileNotFound
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return NetworkFailure
+	}
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return UnknownError
+	}
+	switch aerr.Code() {
+	case request.ErrCodeRequestError, request.CanceledErrorCode, request.ErrCodeResponseTimeout:
+		return NetworkFailure
+	case "ExpiredToken", "InvalidClientTokenId", "SignatureDoesNotMatch", "UnrecognizedClientException",
+		"NoCredentialProviders", "SharedCredsLoad":
+		return InvalidCredential
+	case "AccessDenied":
+		msg := strings.ToLower(aerr.Message())
+		if strings.Contains(msg, "multifactorauthentication") || strings.Contains(msg, "one time pass code") {
+			// sts accepts a code once, the same code in its 30 seconds is rejected as used
+			if strings.Contains(msg, "already") {
+				return ReusedMFACode
+			}
+			return InvalidMFACode
+		}
+		if assumeRole {
+			return RoleAccessDenied
+		}
+		return InvalidCredential
+	}
+	return UnknownError
+}
@@ -0,0 +1,74 @@
+package awslogin
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		assumeRole bool
+		want       ErrorKind
+	}{
+		{
+			"invalid mfa code",
+			awserr.New("AccessDenied", "MultiFactorAuthentication failed with invalid MFA one time pass code. ", nil),
+			false,
+			InvalidMFACode,
+		},
+		{
+			"invalid mfa code on assume role",
+			awserr.New("AccessDenied", "MultiFactorAuthentication failed with invalid MFA one time pass code. ", nil),
+			true,
+			InvalidMFACode,
+		},
+		{
+			"reused mfa code",
+			awserr.New("AccessDenied", "MultiFactorAuthentication failed, the MFA one time pass code has already been used.", nil),
+			true,
+			ReusedMFACode,
+		},
+		{
+			"role access denied",
+			awserr.New("AccessDenied", "User: arn:aws:iam::123456789012:user/u is not authorized to perform: sts:AssumeRole", nil),
+			true,
+			RoleAccessDenied,
+		},
+		{
+			"expired key",
+			awserr.New("InvalidClientTokenId", "The security token included in the request is invalid.", nil),
+			false,
+			InvalidCredential,
+		},
+		{
+			"network",
+			awserr.New(request.ErrCodeRequestError, "send request failed", errors.New("dial tcp: no such host")),
+			false,
+			NetworkFailure,
+		},
+		{
+			"profile not found",
+			fmt.Errorf("%q %w", "dummy", ErrProfileNotFound),
+			false,
+			ProfileNotFound,
+		},
+		{
+			"unknown",
+			errors.New("something"),
+			false,
+			UnknownError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ClassifyError(tt.err, tt.assumeRole))
+		})
+	}
+}
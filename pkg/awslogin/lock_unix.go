@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package awslogin
+
+import (
+	"os"
+	"syscall"
+)
+
+// LockFile takes exclusive lock of path, blocks until lock is taken.
+// The returned function releases the lock.
+func LockFile(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		_ = f.Close()
+	}, nil
+}
@@ -0,0 +1,37 @@
+//go:build windows
+// +build windows
+
+package awslogin
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// lockTimeout is how long to wait for lock, a lock file older than it is left by a crashed process
+const lockTimeout = 30 * time.Second
+
+// LockFile takes exclusive lock of path by creating it, blocks until lock is taken.
+// The returned function releases the lock.
+func LockFile(path string) (func(), error) {
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > lockTimeout {
+			_ = os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock %s", path)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,192 @@
+package awslogin
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// LoginInput is the profile to login and how
+type LoginInput struct {
+	Profile string
+	// Code is the mfa code, not used by role profiles without mfa_serial
+	Code string
+	// Default also saves session and config of profile as default profile
+	Default bool
+	// SourceEnv assumes role with credentials in environments, instead of source of the profile
+	SourceEnv bool
+	// CacheOnly saves session to cli cache instead of credentials file, same as c_cache_only of profile
+	CacheOnly bool
+	// Client settings, overridden by settings of the profile
+	Client ClientConfig
+}
+
+// LoginMFA gets mfa session of profile and saves it to section <profile> of credentials.
+// <profile> must exists in config, <profile>_no_mfa or "profile <profile>_no_mfa" exists in credentials.
+func LoginMFA(c *Config, api AWS, input *LoginInput) (*SessionCredential, error) {
+	profile := input.Profile
+	profileNoMFA := fmt.Sprintf("%s%s", profile, NoMFASuffix)
+	_, err := c.Cred.GetSection(profileNoMFA)
+	if err != nil {
+		profileNoMFA = fmt.Sprintf("profile %s%s", profile, NoMFASuffix)
+		_, err = c.Cred.GetSection(profileNoMFA)
+		if err != nil {
+			return nil, &LoginError{Kind: ProfileNotFound, Profile: profile, Err: fmt.Errorf("%q credential %w", profileNoMFA, ErrProfileNotFound)}
+		}
+	}
+
+	c.logf("mfa profile %q uses long-term keys in [%s]", profile, profileNoMFA)
+
+	// section <profile> must exists
+	confSection := c.Conf.Section("profile " + profile)
+	var confData ConfigData
+	_ = confSection.MapTo(&confData)
+
+	out, err := api.GetMFASession(&GetMFASessionInput{
+		Profile:         profileNoMFA,
+		SerialNumber:    confData.SerialNumber,
+		DurationSeconds: confData.DurationSeconds,
+		Code:            input.Code,
+		Client:          input.Client.WithProfile(&confData),
+	})
+	if err != nil {
+		return nil, NewLoginError(profile, "failed get mfa", err, false)
+	}
+	cred := &SessionCredential{
+		AccessKey:    out.AccessKey,
+		SecretKey:    out.SecretKey,
+		SessionToken: out.SessionToken,
+		Expiration:   out.Expiration,
+	}
+	if err := c.saveSession(cred, &confData, input); err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
+
+// LoginRole assumes role of profile with its source profile, and saves session to section <profile> of credentials.
+// Long-term keys of source profile are used, the "_no_mfa" section if source profile is a mfa profile logged in.
+// Credentials of credential_source, or of environments with SourceEnv, are used instead of source profile if given.
+func LoginRole(c *Config, api AWS, input *LoginInput) (*SessionCredential, error) {
+	profile := input.Profile
+	// section <profile> must exists
+	confSection, err := c.Conf.GetSection("profile " + profile)
+	if err != nil {
+		return nil, &LoginError{Kind: ProfileNotFound, Profile: profile, Err: fmt.Errorf("%q %w", profile, ErrProfileNotFound)}
+	}
+	var confData ConfigData
+	_ = confSection.MapTo(&confData)
+
+	source := confData.CredentialSource
+	if input.SourceEnv {
+		source = CredentialSourceEnvironment
+	}
+	if source != "" {
+		return loginRoleFromSource(c, api, input, &confData, source)
+	}
+
+	sProfile := confData.SourceProfile
+	if sProfile == "" {
+		return nil, fmt.Errorf("'c_source_profile' or 'credential_source' is not present in profile %s", profile)
+	}
+
+	var cred *ini.Section
+	cred, err = c.Cred.GetSection(sProfile)
+	if err != nil {
+		sProfile = fmt.Sprintf("profile %s%s", profile, NoMFASuffix)
+		cred, err = c.Cred.GetSection(sProfile)
+		if err != nil {
+			return nil, &LoginError{Kind: ProfileNotFound, Profile: confData.SourceProfile, Err: fmt.Errorf("source profile %q %w", confData.SourceProfile, ErrProfileNotFound)}
+		}
+	}
+	// session of cache-only source profile is not in credentials, its keys are removed
+	sourceCacheOnly := false
+	if sourceConf, err := c.ProfileConfig(confData.SourceProfile); err == nil {
+		sourceCacheOnly = sourceConf.CacheOnly
+	}
+	if _, err = cred.GetKey("aws_session_token"); err == nil || sourceCacheOnly {
+		// session has token, get no_mfa profile
+		sProfile = fmt.Sprintf("%s%s", confData.SourceProfile, NoMFASuffix)
+		_, err = c.Cred.GetSection(sProfile)
+		if err != nil {
+			sProfile = fmt.Sprintf("profile %s%s", profile, NoMFASuffix)
+			_, err = c.Cred.GetSection(sProfile)
+			if err != nil {
+				return nil, &LoginError{Kind: ProfileNotFound, Profile: confData.SourceProfile, Err: fmt.Errorf("source profile %q %w", sProfile, ErrProfileNotFound)}
+			}
+		}
+	}
+
+	c.logf("role profile %q uses source profile [%s] of %q", profile, sProfile, confData.SourceProfile)
+	out, err := api.GetAssumeRoleSession(&GetAssumeRoleRoleInput{
+		SourceProfile:   sProfile,
+		AssumeRoleArn:   confData.AssumeRoleArn,
+		SerialNumber:    confData.SerialNumber,
+		DurationSeconds: confData.DurationSeconds,
+		Code:            input.Code,
+		Client:          input.Client.WithProfile(&confData),
+	})
+	if err != nil {
+		return nil, &LoginError{
+			Kind:    ClassifyError(err, true),
+			Profile: input.Profile,
+			Source:  sProfile,
+			Err:     fmt.Errorf("failed assume role, %w", err),
+		}
+	}
+	if err := c.saveSession(out, &confData, input); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// loginRoleFromSource assumes role of profile with credentials of source instead of a source profile
+func loginRoleFromSource(c *Config, api AWS, input *LoginInput, confData *ConfigData, source string) (*SessionCredential, error) {
+	if !IsCredentialSource(source) {
+		return nil, fmt.Errorf("invalid credential source %q of profile %s, one of %s", source, input.Profile, strings.Join(CredentialSources, ", "))
+	}
+	c.logf("role profile %q uses credential source %s", input.Profile, source)
+	out, err := api.GetAssumeRoleSession(&GetAssumeRoleRoleInput{
+		CredentialSource: source,
+		AssumeRoleArn:    confData.AssumeRoleArn,
+		SerialNumber:     confData.SerialNumber,
+		DurationSeconds:  confData.DurationSeconds,
+		Code:             input.Code,
+		Client:           input.Client.WithProfile(confData),
+	})
+	if err != nil {
+		return nil, NewLoginError(input.Profile, "failed assume role with "+source+" credentials", err, true)
+	}
+	if err := c.saveSession(out, confData, input); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// IsCredentialSource checks source is one of CredentialSources
+func IsCredentialSource(source string) bool {
+	for _, s := range CredentialSources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
+// saveSession saves cred of logged in profile, also as default profile if asked
+func (c *Config) saveSession(cred *SessionCredential, conf *ConfigData, input *LoginInput) error {
+	if err := c.StoreSession(input.Profile, conf, cred, input.CacheOnly); err != nil {
+		return err
+	}
+	if !input.Default {
+		return nil
+	}
+	if input.CacheOnly || conf.CacheOnly {
+		return c.wireCachedSession("default", input.Profile, conf)
+	}
+	if err := c.SaveDefaultConfig(conf); err != nil {
+		return err
+	}
+	return c.SaveCredentialLocked(cred, "default")
+}
@@ -0,0 +1,193 @@
+package awslogin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/golang/mock/gomock"
+	"github.com/sixleaveakkm/aws-login/internal/awstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginRole(t *testing.T) {
+	dir := awstest.WriteFolder(t, `
+[profile dev]
+mfa_serial = arn:aws:iam::123456789012:mfa/user
+
+[profile admin]
+mfa_serial = arn:aws:iam::123456789012:mfa/user
+c_source_profile = dev
+c_role_arn = arn:aws:iam::210987654321:role/admin
+c_post_login_hook = ./notify.sh
+
+[profile default]
+region = eu-west-1
+c_docker_registries = 123456789012.dkr.ecr.eu-west-1.amazonaws.com
+`, `
+[dev_no_mfa]
+aws_access_key_id = KEY
+aws_secret_access_key = SECRET
+
+[dev]
+aws_access_key_id = SESSION_KEY
+aws_secret_access_key = SESSION_SECRET
+aws_session_token = TOKEN
+`)
+	defer os.RemoveAll(dir)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := NewMockAWS(ctrl)
+	m.EXPECT().GetAssumeRoleSession(gomock.Any()).DoAndReturn(func(input *GetAssumeRoleRoleInput) (*SessionCredential, error) {
+		// long-term keys are used as the source profile is logged in with mfa
+		assert.Equal(t, "dev_no_mfa", input.SourceProfile)
+		assert.Equal(t, "123456", input.Code)
+		assert.Equal(t, "us-east-1", input.Client.Region)
+		return &SessionCredential{AccessKey: "ROLE_KEY", SecretKey: "ROLE_SECRET", SessionToken: "ROLE_TOKEN"}, nil
+	})
+
+	c, err := NewConfig(dir, Options{})
+	assert.NoError(t, err)
+	cred, err := LoginRole(c, m, &LoginInput{Profile: "admin", Code: "123456", Default: true, Client: ClientConfig{Region: "us-east-1"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "ROLE_KEY", cred.AccessKey)
+
+	saved, err := NewConfig(dir, Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, "ROLE_TOKEN", saved.Cred.Section("admin").Key("aws_session_token").String())
+	assert.Equal(t, "ROLE_TOKEN", saved.Cred.Section("default").Key("aws_session_token").String())
+	// default gets mfa_serial of the profile, settings of aws-login stay with the profile and old ones are removed
+	def := saved.Conf.Section("profile default")
+	assert.Equal(t, "arn:aws:iam::123456789012:mfa/user", def.Key("mfa_serial").String())
+	assert.Equal(t, "eu-west-1", def.Key("region").String())
+	for _, key := range []string{"c_source_profile", "c_role_arn", "c_post_login_hook", "c_docker_registries"} {
+		assert.False(t, def.HasKey(key), key)
+	}
+	assert.Equal(t, "KEY", saved.Cred.Section("dev_no_mfa").Key("aws_access_key_id").String())
+	assert.Equal(t, []string{filepath.Join(dir, ConfigFile), filepath.Join(dir, CredentialsFile)}, c.ChangedFiles())
+
+	// failure is of the role profile, so its resync hint and failure count are of it
+	m.EXPECT().GetAssumeRoleSession(gomock.Any()).Return(nil,
+		awserr.New("AccessDenied", "MultiFactorAuthentication failed with invalid MFA one time pass code.", nil))
+	_, err = LoginRole(c, m, &LoginInput{Profile: "admin", Code: "654321", Client: ClientConfig{Region: "us-east-1"}})
+	var loginErr *LoginError
+	assert.True(t, errors.As(err, &loginErr))
+	assert.Equal(t, InvalidMFACode, loginErr.Kind)
+	assert.Equal(t, "admin", loginErr.Profile)
+	assert.Equal(t, "dev_no_mfa", loginErr.Source)
+}
+
+func TestLoginMFAProfileNotFound(t *testing.T) {
+	dir := awstest.WriteFolder(t, "[profile dev]\nmfa_serial = arn:aws:iam::123456789012:mfa/user\n", "")
+	defer os.RemoveAll(dir)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	c, err := NewConfig(dir, Options{DryRun: true})
+	assert.NoError(t, err)
+	_, err = LoginMFA(c, NewMockAWS(ctrl), &LoginInput{Profile: "dev", Code: "123456"})
+	var loginErr *LoginError
+	assert.True(t, errors.As(err, &loginErr))
+	assert.Equal(t, ProfileNotFound, loginErr.Kind)
+	assert.True(t, errors.Is(err, ErrProfileNotFound))
+	assert.Empty(t, c.ChangedFiles())
+}
+
+func TestLoginMFACacheOnly(t *testing.T) {
+	dir := awstest.WriteFolder(t, `
+[profile dev]
+mfa_serial = arn:aws:iam::123456789012:mfa/user
+`, `
+[dev_no_mfa]
+aws_access_key_id = KEY
+aws_secret_access_key = SECRET
+
+[dev]
+aws_access_key_id = KEY
+aws_secret_access_key = SECRET
+`)
+	defer os.RemoveAll(dir)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := NewMockAWS(ctrl)
+	expiration := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+	m.EXPECT().GetMFASession(gomock.Any()).Return(&SessionCredential{
+		AccessKey: "MFA_KEY", SecretKey: "MFA_SECRET", SessionToken: "MFA_TOKEN", Expiration: expiration,
+	}, nil)
+
+	c, err := NewConfig(dir, Options{})
+	assert.NoError(t, err)
+	_, err = LoginMFA(c, m, &LoginInput{Profile: "dev", Code: "123456", CacheOnly: true, Default: true})
+	assert.NoError(t, err)
+
+	saved, err := NewConfig(dir, Options{})
+	assert.NoError(t, err)
+	section := saved.Conf.Section("profile dev")
+	// the running binary is found by absolute path, not by PATH of sdks
+	exe, _ := os.Executable()
+	assert.True(t, filepath.IsAbs(exe))
+	assert.Equal(t, exe+" credential-process --profile dev", section.Key("credential_process").String())
+	assert.Equal(t, "true", section.Key("c_cache_only").String())
+	def := saved.Conf.Section("profile default")
+	assert.Equal(t, section.Key("credential_process").String(), def.Key("credential_process").String())
+	assert.False(t, def.HasKey("c_cache_only"))
+	// no keys left for sdks to prefer over credential_process, long-term keys are kept
+	assert.False(t, saved.Cred.Section("dev").HasKey("aws_access_key_id"))
+	assert.Equal(t, "KEY", saved.Cred.Section("dev_no_mfa").Key("aws_access_key_id").String())
+	assert.NotContains(t, Render(saved.Cred), "MFA_TOKEN")
+
+	conf, _ := saved.ProfileConfig("dev")
+	path := saved.CLICachePath(conf)
+	assert.Equal(t, filepath.Join(dir, "cli", "cache", CLICacheKey(conf)+".json"), path)
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	cred, err := saved.LoadSession("dev")
+	assert.NoError(t, err)
+	assert.Equal(t, "MFA_TOKEN", cred.SessionToken)
+	assert.True(t, expiration.Equal(cred.Expiration))
+}
+
+func TestLoginRoleCredentialSource(t *testing.T) {
+	dir := awstest.WriteFolder(t, `
+[profile deploy]
+c_role_arn = arn:aws:iam::210987654321:role/deploy
+credential_source = EcsContainer
+
+[profile admin]
+c_source_profile = dev
+c_role_arn = arn:aws:iam::210987654321:role/admin
+`, "")
+	defer os.RemoveAll(dir)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := NewMockAWS(ctrl)
+	var sources []string
+	m.EXPECT().GetAssumeRoleSession(gomock.Any()).Times(2).DoAndReturn(func(input *GetAssumeRoleRoleInput) (*SessionCredential, error) {
+		assert.Empty(t, input.SourceProfile)
+		sources = append(sources, input.CredentialSource)
+		return &SessionCredential{AccessKey: "ROLE_KEY", SecretKey: "ROLE_SECRET", SessionToken: "ROLE_TOKEN"}, nil
+	})
+
+	c, err := NewConfig(dir, Options{DryRun: true})
+	assert.NoError(t, err)
+	_, err = LoginRole(c, m, &LoginInput{Profile: "deploy"})
+	assert.NoError(t, err)
+	// source profile dev is not needed with credentials in environments
+	_, err = LoginRole(c, m, &LoginInput{Profile: "admin", SourceEnv: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{CredentialSourceEcsContainer, CredentialSourceEnvironment}, sources)
+	assert.Equal(t, "ROLE_TOKEN", c.Cred.Section("deploy").Key("aws_session_token").String())
+
+	c.Conf.Section("profile deploy").Key("credential_source").SetValue("Unknown")
+	_, err = LoginRole(c, m, &LoginInput{Profile: "deploy"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,253 @@
+package awslogin
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	aws_ "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/ec2rolecreds"
+	"github.com/aws/aws-sdk-go/aws/defaults"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// ClientConfig holds the settings applied when aws-login creates sdk sessions and clients.
+// Empty values keep the sdk defaults.
+type ClientConfig struct {
+	Region string
+
+	// STSEndpoint and IAMEndpoint replace the resolved service endpoint url,
+	// e.g. a vpc endpoint or a local emulator.
+	STSEndpoint string
+	IAMEndpoint string
+
+	// STSRegionalEndpoints is "regional" or "legacy", same as `sts_regional_endpoints` of aws cli.
+	STSRegionalEndpoints string
+	// UseFIPS is nil to keep the sdk default, false turns off fips endpoints set in environment or profile.
+	UseFIPS *bool
+
+	// CABundle is a pem file of certificates to trust instead of system ones, e.g. of a tls-inspecting proxy.
+	CABundle string
+	// Proxy is the https proxy url, environments HTTPS_PROXY and NO_PROXY are used if empty.
+	Proxy string
+	// Timeout of each http request, no timeout if zero.
+	Timeout time.Duration
+
+	// Logf receives debug logs of sessions and sdk requests, which are not redacted.
+	// Nothing is logged if nil.
+	Logf func(format string, args ...interface{})
+}
+
+func (cc ClientConfig) logf(format string, args ...interface{}) {
+	if cc.Logf != nil {
+		cc.Logf(format, args...)
+	}
+}
+
+// WithProfile returns a copy of client settings with values set in profile config applied.
+func (cc ClientConfig) WithProfile(conf *ConfigData) ClientConfig {
+	if conf == nil {
+		return cc
+	}
+	if conf.Region != "" {
+		cc.Region = conf.Region
+	}
+	if conf.STSEndpoint != "" {
+		cc.STSEndpoint = conf.STSEndpoint
+	}
+	if conf.IAMEndpoint != "" {
+		cc.IAMEndpoint = conf.IAMEndpoint
+	}
+	if conf.STSRegionalEndpoints != "" {
+		cc.STSRegionalEndpoints = conf.STSRegionalEndpoints
+	}
+	if conf.UseFIPSEndpoint != nil {
+		cc.UseFIPS = aws_.Bool(*conf.UseFIPSEndpoint)
+	}
+	if conf.CABundle != "" {
+		cc.CABundle = conf.CABundle
+	}
+	return cc
+}
+
+// Credential sources of role profiles, same as `credential_source` of aws cli
+const (
+	CredentialSourceEnvironment         = "Environment"
+	CredentialSourceEc2InstanceMetadata = "Ec2InstanceMetadata"
+	CredentialSourceEcsContainer        = "EcsContainer"
+)
+
+// CredentialSources are valid values of credential_source
+var CredentialSources = []string{CredentialSourceEnvironment, CredentialSourceEc2InstanceMetadata, CredentialSourceEcsContainer}
+
+// NewSession creates a session of profile with client settings applied.
+func NewSession(profile string, client ClientConfig) (*session.Session, error) {
+	return newSession(session.Options{Profile: profile}, client, fmt.Sprintf("profile [%s]", profile))
+}
+
+// NewSourceSession creates a session with credentials of source, one of CredentialSources, instead of a profile.
+// Shared config and credentials files are not read.
+func NewSourceSession(source string, client ClientConfig) (*session.Session, error) {
+	sess, err := newSession(session.Options{SharedConfigState: session.SharedConfigDisable}, client, "credential source "+source)
+	if err != nil {
+		return nil, err
+	}
+	var creds *credentials.Credentials
+	switch source {
+	case CredentialSourceEnvironment:
+		creds = credentials.NewEnvCredentials()
+	case CredentialSourceEc2InstanceMetadata:
+		creds = ec2rolecreds.NewCredentialsWithClient(ec2metadata.New(sess))
+	case CredentialSourceEcsContainer:
+		if os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") == "" && os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") == "" {
+			return nil, fmt.Errorf("credential source %s requires AWS_CONTAINER_CREDENTIALS_RELATIVE_URI or AWS_CONTAINER_CREDENTIALS_FULL_URI", source)
+		}
+		creds = credentials.NewCredentials(defaults.RemoteCredProvider(*sess.Config, sess.Handlers))
+	default:
+		return nil, fmt.Errorf("invalid credential source %q, one of %s", source, strings.Join(CredentialSources, ", "))
+	}
+	return sess.Copy(&aws_.Config{Credentials: creds}), nil
+}
+
+// NewCredentialSession creates a session signing with cred instead of a profile, shared config and credentials files are not read.
+func NewCredentialSession(cred *SessionCredential, client ClientConfig) (*session.Session, error) {
+	sess, err := newSession(session.Options{SharedConfigState: session.SharedConfigDisable}, client, "session credential")
+	if err != nil {
+		return nil, err
+	}
+	creds := credentials.NewStaticCredentials(cred.AccessKey, cred.SecretKey, cred.SessionToken)
+	return sess.Copy(&aws_.Config{Credentials: creds}), nil
+}
+
+// newSession creates session of opts with client settings applied, from describes credentials in debug logs
+func newSession(opts session.Options, client ClientConfig, from string) (*session.Session, error) {
+	cfg := aws_.NewConfig()
+	if client.Region != "" {
+		cfg.WithRegion(client.Region)
+	}
+	if client.STSRegionalEndpoints != "" {
+		sre, err := endpoints.GetSTSRegionalEndpoint(client.STSRegionalEndpoints)
+		if err != nil {
+			return nil, err
+		}
+		cfg.WithSTSRegionalEndpoint(sre)
+	}
+	if client.UseFIPS != nil {
+		cfg.WithUseFIPSEndpoint(*client.UseFIPS)
+	}
+	httpClient, err := NewHTTPClient(client)
+	if err != nil {
+		return nil, err
+	}
+	cfg.WithHTTPClient(httpClient)
+	opts.Config = *cfg
+	// ca bundle given to sdk directly, it takes priority over AWS_CA_BUNDLE read by sdk.
+	if client.CABundle != "" {
+		pem, err := ioutil.ReadFile(client.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca bundle, %w", err)
+		}
+		opts.CustomCABundle = bytes.NewReader(pem)
+	}
+	if client.Logf != nil {
+		opts.Config.WithLogLevel(aws_.LogDebugWithRequestRetries | aws_.LogDebugWithRequestErrors)
+		opts.Config.WithLogger(aws_.LoggerFunc(func(args ...interface{}) {
+			client.Logf("%s", fmt.Sprint(args...))
+		}))
+	}
+	client.logf("creating session of %s, region=%q sts_endpoint=%q iam_endpoint=%q sts_regional_endpoints=%q fips=%s ca_bundle=%q proxy=%q timeout=%s",
+		from, client.Region, client.STSEndpoint, client.IAMEndpoint, client.STSRegionalEndpoints, fipsForLog(client.UseFIPS), client.CABundle, proxyForLog(client.Proxy), client.Timeout)
+	sess, err := session.NewSessionWithOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if client.Logf != nil {
+		sess.Handlers.Complete.PushBack(func(r *request.Request) {
+			status := 0
+			if r.HTTPResponse != nil {
+				status = r.HTTPResponse.StatusCode
+			}
+			client.Logf("%s.%s %s status=%d request_id=%s retries=%d error=%v",
+				r.ClientInfo.ServiceName, r.Operation.Name, r.HTTPRequest.URL.Host, status, r.RequestID, r.RetryCount, r.Error)
+		})
+	}
+	return sess, nil
+}
+
+// fipsForLog is "default" if fips is not set, to tell it from false
+func fipsForLog(fips *bool) string {
+	if fips == nil {
+		return "default"
+	}
+	return strconv.FormatBool(*fips)
+}
+
+// proxyForLog removes user info of proxy url, which may hold credentials of the proxy
+func proxyForLog(proxy string) string {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	return u.String()
+}
+
+// NewSTSClient creates sts client, using custom endpoint if given
+func NewSTSClient(sess *session.Session, client ClientConfig) *sts.STS {
+	cfg := aws_.NewConfig()
+	if client.STSEndpoint != "" {
+		cfg.WithEndpoint(client.STSEndpoint)
+	}
+	return sts.New(sess, cfg)
+}
+
+// NewIAMClient creates iam client, using custom endpoint if given
+func NewIAMClient(sess *session.Session, client ClientConfig) *iam.IAM {
+	cfg := aws_.NewConfig()
+	if client.IAMEndpoint != "" {
+		cfg.WithEndpoint(client.IAMEndpoint)
+	}
+	return iam.New(sess, cfg)
+}
+
+// NewHTTPClient creates http client used by sdk sessions, with proxy, ca bundle and timeout applied.
+// Clients of other servers than aws, e.g. vault, trust the same ca bundle.
+func NewHTTPClient(client ClientConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if client.CABundle != "" {
+		pem, err := ioutil.ReadFile(client.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca bundle, %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in ca bundle %s", client.CABundle)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	if client.Proxy != "" {
+		proxy, err := url.Parse(client.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url %q, %w", client.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   client.Timeout,
+	}, nil
+}
@@ -0,0 +1,225 @@
+package awslogin
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/ini.v1"
+)
+
+// testCredentialsFile has long-term keys of profile "dummy_no_mfa"
+const testCredentialsFile = "../../test_resource/credentials"
+
+const fakeGetSessionTokenResponse = `<GetSessionTokenResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetSessionTokenResult>
+    <Credentials>
+      <AccessKeyId>FAKE_KEY_ID</AccessKeyId>
+      <SecretAccessKey>FAKE_SECRET</SecretAccessKey>
+      <SessionToken>FAKE_TOKEN</SessionToken>
+      <Expiration>2030-01-01T00:00:00Z</Expiration>
+    </Credentials>
+  </GetSessionTokenResult>
+  <ResponseMetadata><RequestId>fake</RequestId></ResponseMetadata>
+</GetSessionTokenResponse>`
+
+const fakeAssumeRoleResponse = `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleResult>
+    <Credentials>
+      <AccessKeyId>FAKE_ROLE_KEY_ID</AccessKeyId>
+      <SecretAccessKey>FAKE_ROLE_SECRET</SecretAccessKey>
+      <SessionToken>FAKE_ROLE_TOKEN</SessionToken>
+      <Expiration>2030-01-01T00:00:00Z</Expiration>
+    </Credentials>
+  </AssumeRoleResult>
+  <ResponseMetadata><RequestId>fake</RequestId></ResponseMetadata>
+</AssumeRoleResponse>`
+
+func TestClientConfigWithProfile(t *testing.T) {
+	global := ClientConfig{STSEndpoint: "https://global", STSRegionalEndpoints: "legacy"}
+	merged := global.WithProfile(&ConfigData{
+		Region:               "ap-northeast-1",
+		STSRegionalEndpoints: "regional",
+		UseFIPSEndpoint:      aws.Bool(true),
+	})
+	assert.Equal(t, ClientConfig{
+		Region:               "ap-northeast-1",
+		STSEndpoint:          "https://global",
+		STSRegionalEndpoints: "regional",
+		UseFIPS:              aws.Bool(true),
+	}, merged)
+	assert.Equal(t, global, global.WithProfile(nil))
+
+	fips := ClientConfig{UseFIPS: aws.Bool(true)}
+	assert.True(t, *fips.WithProfile(&ConfigData{}).UseFIPS)
+	assert.False(t, *fips.WithProfile(&ConfigData{UseFIPSEndpoint: aws.Bool(false)}).UseFIPS)
+
+	file, _ := ini.Load([]byte("[profile dev]\nuse_fips_endpoint = false\n"))
+	conf := &ConfigData{}
+	assert.NoError(t, file.Section("profile dev").MapTo(conf))
+	assert.False(t, *fips.WithProfile(conf).UseFIPS)
+}
+
+func TestGetMFASessionCustomEndpoint(t *testing.T) {
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+		_ = r.ParseForm()
+		assert.Equal(t, "GetSessionToken", r.Form.Get("Action"))
+		assert.Equal(t, "123456", r.Form.Get("TokenCode"))
+		_, _ = fmt.Fprint(w, fakeGetSessionTokenResponse)
+	}))
+	defer server.Close()
+
+	credFile, _ := filepath.Abs(testCredentialsFile)
+	_ = os.Setenv("AWS_SHARED_CREDENTIALS_FILE", credFile)
+	defer os.Unsetenv("AWS_SHARED_CREDENTIALS_FILE")
+
+	out, err := AWSImpl{}.GetMFASession(&GetMFASessionInput{
+		Profile:         "dummy_no_mfa",
+		SerialNumber:    "arn:aws:iam::123456789012:mfa/user",
+		DurationSeconds: 900,
+		Code:            "123456",
+		Client:          ClientConfig{Region: "us-east-1", STSEndpoint: server.URL},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "FAKE_KEY_ID", out.AccessKey)
+	assert.Equal(t, "FAKE_TOKEN", out.SessionToken)
+	assert.Contains(t, received.Get("Authorization"), "DUMMY_KEY_ID")
+}
+
+func TestNewSessionInvalidRegionalEndpoints(t *testing.T) {
+	_, err := NewSession("dummy", ClientConfig{STSRegionalEndpoints: "somewhere"})
+	assert.Error(t, err)
+}
+
+func TestGetMFASessionCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, fakeGetSessionTokenResponse)
+	}))
+	defer server.Close()
+
+	bundle, err := ioutil.TempFile("", "ca-bundle-*.pem")
+	assert.NoError(t, err)
+	defer os.Remove(bundle.Name())
+	_ = pem.Encode(bundle, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	_ = bundle.Close()
+
+	credFile, _ := filepath.Abs(testCredentialsFile)
+	_ = os.Setenv("AWS_SHARED_CREDENTIALS_FILE", credFile)
+	defer os.Unsetenv("AWS_SHARED_CREDENTIALS_FILE")
+
+	input := &GetMFASessionInput{
+		Profile:         "dummy_no_mfa",
+		SerialNumber:    "arn:aws:iam::123456789012:mfa/user",
+		DurationSeconds: 900,
+		Code:            "123456",
+		Client:          ClientConfig{Region: "us-east-1", STSEndpoint: server.URL, Timeout: 5 * time.Second},
+	}
+	_, err = AWSImpl{}.GetMFASession(input)
+	assert.Error(t, err, "untrusted certificate must be rejected")
+
+	input.Client.CABundle = bundle.Name()
+	out, err := AWSImpl{}.GetMFASession(input)
+	assert.NoError(t, err)
+	assert.Equal(t, "FAKE_KEY_ID", out.AccessKey)
+}
+
+func TestGetMFASessionProxy(t *testing.T) {
+	var proxiedHost string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedHost = r.URL.Host
+		_, _ = fmt.Fprint(w, fakeGetSessionTokenResponse)
+	}))
+	defer proxy.Close()
+
+	credFile, _ := filepath.Abs(testCredentialsFile)
+	_ = os.Setenv("AWS_SHARED_CREDENTIALS_FILE", credFile)
+	defer os.Unsetenv("AWS_SHARED_CREDENTIALS_FILE")
+
+	out, err := AWSImpl{}.GetMFASession(&GetMFASessionInput{
+		Profile:         "dummy_no_mfa",
+		SerialNumber:    "arn:aws:iam::123456789012:mfa/user",
+		DurationSeconds: 900,
+		Code:            "123456",
+		Client: ClientConfig{
+			Region:      "us-east-1",
+			STSEndpoint: "http://sts.internal.example",
+			Proxy:       proxy.URL,
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "FAKE_KEY_ID", out.AccessKey)
+	assert.Equal(t, "sts.internal.example", proxiedHost)
+}
+
+func TestGetAssumeRoleSessionWithoutMFA(t *testing.T) {
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		form = r.Form
+		_, _ = fmt.Fprint(w, fakeAssumeRoleResponse)
+	}))
+	defer server.Close()
+
+	credFile, _ := filepath.Abs(testCredentialsFile)
+	_ = os.Setenv("AWS_SHARED_CREDENTIALS_FILE", credFile)
+	defer os.Unsetenv("AWS_SHARED_CREDENTIALS_FILE")
+
+	tests := []struct {
+		name     string
+		input    GetAssumeRoleRoleInput
+		duration string
+	}{
+		{"no serial", GetAssumeRoleRoleInput{DurationSeconds: 3600}, "3600"},
+		{"no serial and duration", GetAssumeRoleRoleInput{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form = nil
+			input := tt.input
+			input.SourceProfile = "dummy_no_mfa"
+			input.AssumeRoleArn = "arn:aws:iam::210987654321:role/admin"
+			input.Client = ClientConfig{Region: "us-east-1", STSEndpoint: server.URL}
+			out, err := AWSImpl{}.GetAssumeRoleSession(&input)
+			assert.NoError(t, err)
+			assert.Equal(t, "FAKE_ROLE_TOKEN", out.SessionToken)
+			assert.Equal(t, "AssumeRole", form.Get("Action"))
+			assert.Equal(t, tt.duration, form.Get("DurationSeconds"))
+			assert.NotContains(t, form, "SerialNumber")
+			assert.NotContains(t, form, "TokenCode")
+		})
+	}
+}
+
+func TestGetAssumeRoleSessionFromEnvironment(t *testing.T) {
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+		_, _ = fmt.Fprint(w, fakeAssumeRoleResponse)
+	}))
+	defer server.Close()
+
+	_ = os.Setenv("AWS_ACCESS_KEY_ID", "ENV_KEY_ID")
+	_ = os.Setenv("AWS_SECRET_ACCESS_KEY", "ENV_SECRET")
+	defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+	defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+
+	out, err := AWSImpl{}.GetAssumeRoleSession(&GetAssumeRoleRoleInput{
+		CredentialSource: CredentialSourceEnvironment,
+		AssumeRoleArn:    "arn:aws:iam::210987654321:role/deploy",
+		Client:           ClientConfig{Region: "us-east-1", STSEndpoint: server.URL},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "FAKE_ROLE_TOKEN", out.SessionToken)
+	assert.Contains(t, received.Get("Authorization"), "ENV_KEY_ID")
+}
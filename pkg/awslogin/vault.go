@@ -0,0 +1,68 @@
+package awslogin
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+
+	aws_ "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// vaultServerIDHeader is signed with the request, so the payload is only accepted by the vault server expecting it
+const vaultServerIDHeader = "X-Vault-AWS-IAM-Server-ID"
+
+// VaultLoginPayload is the login body of iam method of vault aws auth
+type VaultLoginPayload struct {
+	Role    string `json:"role,omitempty"`
+	Method  string `json:"iam_http_request_method"`
+	URL     string `json:"iam_request_url"`
+	Headers string `json:"iam_request_headers"`
+	Body    string `json:"iam_request_body"`
+}
+
+// NewVaultLoginPayload signs sts GetCallerIdentity with cred for vault, no request is sent.
+// serverID is set to X-Vault-AWS-IAM-Server-ID header if not empty.
+// Only Region, STSEndpoint and Logf of client are used, they should be the ones vault verifies with,
+// other endpoint settings would change the signed url from the one vault expects.
+func NewVaultLoginPayload(cred *SessionCredential, role string, serverID string, client ClientConfig) (*VaultLoginPayload, error) {
+	signer := ClientConfig{
+		Region:      client.Region,
+		STSEndpoint: client.STSEndpoint,
+		// global endpoint is kept for us-east-1, it is what vault verifies with by default
+		STSRegionalEndpoints: "legacy",
+		UseFIPS:              aws_.Bool(false),
+		Logf:                 client.Logf,
+	}
+	if signer.Region == "" {
+		signer.Region = "us-east-1"
+	} else if signer.Region != "us-east-1" {
+		signer.STSRegionalEndpoints = "regional"
+	}
+	sess, err := NewCredentialSession(cred, signer)
+	if err != nil {
+		return nil, err
+	}
+	req, _ := NewSTSClient(sess, signer).GetCallerIdentityRequest(&sts.GetCallerIdentityInput{})
+	if serverID != "" {
+		req.HTTPRequest.Header.Set(vaultServerIDHeader, serverID)
+	}
+	if err := req.Sign(); err != nil {
+		return nil, err
+	}
+	headers, err := json.Marshal(req.HTTPRequest.Header)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(req.HTTPRequest.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &VaultLoginPayload{
+		Role:    role,
+		Method:  req.HTTPRequest.Method,
+		URL:     base64.StdEncoding.EncodeToString([]byte(req.HTTPRequest.URL.String())),
+		Headers: base64.StdEncoding.EncodeToString(headers),
+		Body:    base64.StdEncoding.EncodeToString(body),
+	}, nil
+}
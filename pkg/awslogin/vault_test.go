@@ -0,0 +1,59 @@
+package awslogin
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewVaultLoginPayload(t *testing.T) {
+	cred := &SessionCredential{AccessKey: "KEY", SecretKey: "SECRET", SessionToken: "TOKEN"}
+	payload, err := NewVaultLoginPayload(cred, "dev", "vault.example.com", ClientConfig{})
+	assert.NoError(t, err)
+	assert.Equal(t, "dev", payload.Role)
+	assert.Equal(t, http.MethodPost, payload.Method)
+
+	decode := func(s string) string {
+		b, err := base64.StdEncoding.DecodeString(s)
+		assert.NoError(t, err)
+		return string(b)
+	}
+	assert.Equal(t, "https://sts.amazonaws.com/", decode(payload.URL))
+	assert.Equal(t, "Action=GetCallerIdentity&Version=2011-06-15", decode(payload.Body))
+
+	var headers http.Header
+	assert.NoError(t, json.Unmarshal([]byte(decode(payload.Headers)), &headers))
+	assert.Equal(t, "vault.example.com", headers.Get("X-Vault-AWS-IAM-Server-ID"))
+	assert.Equal(t, "TOKEN", headers.Get("X-Amz-Security-Token"))
+	assert.True(t, strings.Contains(headers.Get("Authorization"), "x-vault-aws-iam-server-id"))
+}
+
+func TestNewVaultLoginPayloadIgnoresProfileEndpoints(t *testing.T) {
+	_ = os.Setenv("AWS_STS_REGIONAL_ENDPOINTS", "regional")
+	defer os.Unsetenv("AWS_STS_REGIONAL_ENDPOINTS")
+	cred := &SessionCredential{AccessKey: "KEY", SecretKey: "SECRET", SessionToken: "TOKEN"}
+
+	tests := []struct {
+		name   string
+		client ClientConfig
+		want   string
+	}{
+		{"global", ClientConfig{STSRegionalEndpoints: "regional", UseFIPS: aws.Bool(true)}, "https://sts.amazonaws.com/"},
+		{"regional", ClientConfig{Region: "eu-west-1"}, "https://sts.eu-west-1.amazonaws.com/"},
+		{"vault endpoint", ClientConfig{STSEndpoint: "https://sts.example.com"}, "https://sts.example.com/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := NewVaultLoginPayload(cred, "dev", "", tt.client)
+			assert.NoError(t, err)
+			url, _ := base64.StdEncoding.DecodeString(payload.URL)
+			assert.Equal(t, tt.want, string(url))
+		})
+	}
+}
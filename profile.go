@@ -5,7 +5,6 @@ import (
 	"os"
 	"strings"
 
-	a "github.com/logrusorgru/aurora"
 	"github.com/urfave/cli/v2"
 )
 
@@ -14,7 +13,7 @@ func getProfile(c *cli.Context) string {
 	if profile == "" {
 		if envProfile := os.Getenv("AWS_PROFILE"); envProfile != "" && !strings.HasSuffix(envProfile, "_no_mfa") {
 			profile = envProfile
-			fmt.Print("Using AWS_PROFILE environment: ", a.Bold(a.Blue(fmt.Sprintf("%s\n", envProfile))))
+			fmt.Fprint(infoOut(), "Using AWS_PROFILE environment: ", a.Bold(a.Blue(fmt.Sprintf("%s\n", envProfile))))
 		} else {
 			profile = "default"
 		}
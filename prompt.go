@@ -2,29 +2,101 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
-
-	a "github.com/logrusorgru/aurora"
 )
 
 // promptSixDigitCode, prompt user to enter six digit code and return the code with enter
 // If input if incorrect, prompt to re-enter
-func promptSixDigitCode() string {
+// It returns error without prompt when not interactive, see isInteractive
+func promptSixDigitCode() (string, error) {
+	return promptSixDigitCodeAs("MFA code")
+}
+
+// promptSixDigitCodeAs prompts six digit code with label, e.g. "First MFA code"
+func promptSixDigitCodeAs(label string) (string, error) {
+	if !isInteractive() {
+		return "", errors.New("mfa code is required, give it as argument when not running in a terminal")
+	}
 	reader := bufio.NewReader(os.Stdin)
-	fmt.Print(a.Bold(a.BrightCyan("MFA code: ")))
+	fmt.Print(a.Bold(a.BrightCyan(label + ": ")))
 
 	for {
-		text, _ := reader.ReadString('\n')
+		text, err := reader.ReadString('\n')
+		if err != nil {
+			return "", fmt.Errorf("failed to read mfa code, %w", err)
+		}
 		text = strings.Replace(text, "\n", "", -1)
 		if isSixDigit(text) {
-			return text
+			return text, nil
+		}
+
+		fmt.Printf("%s, %s",
+			a.Bold(a.BrightRed("x Invalid Input")),
+			a.Bold(a.BrightCyan(label+": ")),
+		)
+	}
+}
+
+// promptMFADevice lets user pick one of devices by its number
+// It returns error without prompt when not interactive, see isInteractive
+func promptMFADevice(devices []MFADevice) (string, error) {
+	if !isInteractive() {
+		return "", errors.New("several mfa devices found, choose one with --serial-number when not running in a terminal")
+	}
+	fmt.Println("Several mfa devices found:")
+	for i, d := range devices {
+		fmt.Printf("  %s %s\n", a.Bold(fmt.Sprintf("%d)", i+1)), describeMFADevice(d))
+	}
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Print(a.Bold(a.BrightCyan("Device number: ")))
+
+	for {
+		text, err := reader.ReadString('\n')
+		if err != nil {
+			return "", fmt.Errorf("failed to read device number, %w", err)
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(text)); err == nil && n >= 1 && n <= len(devices) {
+			return devices[n-1].SerialNumber, nil
 		}
 
 		fmt.Printf("%s, %s",
 			a.Bold(a.BrightRed("x Invalid Input")),
-			a.Bold(a.BrightCyan("MFA code: ")),
+			a.Bold(a.BrightCyan("Device number: ")),
 		)
 	}
 }
+
+// describeMFADevice shows serial with kind and enable date of device
+func describeMFADevice(d MFADevice) string {
+	kind := "hardware"
+	if d.Virtual {
+		kind = "virtual"
+	}
+	enabled := "unknown"
+	if !d.EnableDate.IsZero() {
+		enabled = d.EnableDate.Local().Format("2006-01-02")
+	}
+	return fmt.Sprintf("%s (%s, enabled %s)", d.SerialNumber, kind, enabled)
+}
+
+// promptConsecutiveCodes prompts two consecutive codes of a device, the second one must be a new code
+func promptConsecutiveCodes() (string, string, error) {
+	code1, err := promptSixDigitCodeAs("First MFA code")
+	if err != nil {
+		return "", "", err
+	}
+	for {
+		code2, err := promptSixDigitCodeAs("Second MFA code (wait for the next one)")
+		if err != nil {
+			return "", "", err
+		}
+		if code2 != code1 {
+			return code1, code2, nil
+		}
+		fmt.Println(a.Bold(a.BrightRed("x Same as the first code")))
+	}
+}
@@ -4,9 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
 	"github.com/urfave/cli/v2"
-	"gopkg.in/ini.v1"
 )
 
 var RoleCommand = &cli.Command{
@@ -30,6 +31,10 @@ var RoleCommand = &cli.Command{
 			Aliases: []string{"s"},
 			Usage:   "source profile used to assume role",
 		},
+		&cli.StringFlag{
+			Name:  CredentialSource,
+			Usage: "where source credentials come from instead of source profile, one of Environment, Ec2InstanceMetadata, EcsContainer",
+		},
 		&cli.StringFlag{
 			Name:    RoleArn,
 			Aliases: []string{"r"},
@@ -53,27 +58,52 @@ var RoleCommand = &cli.Command{
 // }
 
 func configRoleAction(c *cli.Context) error {
-	config := NewConfig(awsFoldPath)
+	config, err := NewConfig(awsFoldPath)
+	if err != nil {
+		return err
+	}
 	profile := getProfile(c)
 	sourceProfile := c.String(SourceProfile)
-	if !config.listPossibleProfiles().Contains(ShortSectionName(sourceProfile)) {
+	credentialSource := c.String(CredentialSource)
+	if credentialSource != "" {
+		if sourceProfile != "" {
+			return errors.New("only one of source profile and credential source could be given")
+		}
+		if !awslogin.IsCredentialSource(credentialSource) {
+			return fmt.Errorf("credential source must be one of %s", strings.Join(awslogin.CredentialSources, ", "))
+		}
+	} else if !config.ListPossibleProfiles().Contains(awslogin.ShortSectionName(sourceProfile)) {
 		return errors.New("input profile is not valid")
 	}
 
 	configData := &ConfigData{
-		SerialNumber:    c.String(SerialNumber),
-		DurationSeconds: c.Int64(Duration),
-		SourceProfile:   c.String(SourceProfile),
-		AssumeRoleArn:   c.String(RoleArn),
+		SerialNumber:     c.String(SerialNumber),
+		DurationSeconds:  c.Int64(Duration),
+		SourceProfile:    sourceProfile,
+		AssumeRoleArn:    c.String(RoleArn),
+		CredentialSource: credentialSource,
 	}
 
 	if configData.DurationSeconds == 0 || c.Int64(Duration) != DefaultDurationSeconds {
 		configData.DurationSeconds = c.Int64(Duration)
 	}
 
+	if credentialSource != "" {
+		// sessions of instance and container roles are chained, capped at 1 hour; sts uses the default of role if not given
+		if !c.IsSet(Duration) {
+			configData.DurationSeconds = 0
+		}
+		// credentials of source have no mfa session, serial is only needed if role requires mfa
+		if err := config.SaveConfig(configData, profile); err != nil {
+			return err
+		}
+		printResult(&ConfigResult{Profile: profile, Kind: Role, FilesChanged: config.ChangedFiles()})
+		return nil
+	}
+
 	// Check original profile, if original profile contains token (one time),
 	// maximum duration is 1 hour. start gui to confirm
-	originProfile, err := config.loadCredential(configData.SourceProfile)
+	originProfile, err := config.LoadCredential(configData.SourceProfile)
 	if err != nil {
 
 		fmt.Printf("source profile: (%s) doesn't exists", configData.SourceProfile)
@@ -92,70 +122,10 @@ func configRoleAction(c *cli.Context) error {
 		os.Exit(1)
 	} else {
 		configData.SerialNumber = serial
-		config.saveConfig(configData, profile, configFile_)
-	}
-	return nil
-}
-
-func getRoleSession(input *GetAssumeRoleRoleInput) (*SessionCredential, error) {
-	if input.SourceProfile == "" {
-		return nil, fmt.Errorf("'origin_profile' is not present in %s", input.SourceProfile)
-	}
-	return aws.GetAssumeRoleSession(input)
-}
-
-func loginForRole(config *Config, profile string, code string, toDefault bool) error {
-	// section <profile> must exists
-	confSection, err := config.Conf.GetSection(Profile + " " + profile)
-	if err != nil {
-		return NoProfileError
-	}
-	var confData ConfigData
-	_ = confSection.MapTo(&confData)
-
-	sProfile := confData.SourceProfile
-
-	var cred *ini.Section
-	cred, err = config.Cred.GetSection(sProfile)
-	if err != nil {
-		sProfile = fmt.Sprintf("profile %s%s", profile, excludeConfigPostfix)
-		cred, err = config.Cred.GetSection(sProfile)
-		if err != nil {
-			return NoProfileError
-		}
-	}
-	if _, err = cred.GetKey("aws_session_token"); err == nil {
-		// session has token, get no_mfa profile
-		sProfile = fmt.Sprintf("%s%s", confData.SourceProfile, excludeConfigPostfix)
-		_, err = config.Cred.GetSection(sProfile)
-		if err != nil {
-			sProfile = fmt.Sprintf("profile %s%s", profile, excludeConfigPostfix)
-			_, err = config.Cred.GetSection(sProfile)
-			if err != nil {
-				return NoProfileError
-			}
+		if err := config.SaveConfig(configData, profile); err != nil {
+			return err
 		}
 	}
-
-	confData.SourceProfile = sProfile
-	input := &GetAssumeRoleRoleInput{
-		SourceProfile:   confData.SourceProfile,
-		AssumeRoleArn:   confData.AssumeRoleArn,
-		SerialNumber:    confData.SerialNumber,
-		DurationSeconds: confData.DurationSeconds,
-		Code:            code,
-	}
-
-	var out *SessionCredential
-	out, err = getRoleSession(input)
-	if err != nil {
-		return fmt.Errorf("failed get mfa, %v\n", err)
-	}
-	config.saveCredential(out, profile, credentialsFile_)
-
-	if toDefault {
-		config.saveConfig(&confData, "default", configFile_)
-		config.saveCredential(out, "default", credentialsFile_)
-	}
+	printResult(&ConfigResult{Profile: profile, Kind: Role, FilesChanged: config.ChangedFiles()})
 	return nil
 }
@@ -0,0 +1,51 @@
+//go:build ignore
+// +build ignore
+
+// gen_completion writes completion scripts in bin/ into completion_scripts.go,
+// as go 1.13 could not embed files. Run by `go generate` in the root folder.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+const output = "completion_scripts.go"
+
+var scripts = []struct {
+	name string
+	path string
+}{
+	{"bashCompletion", filepath.Join("bin", "bash", "_aws-login")},
+	{"zshCompletion", filepath.Join("bin", "zsh", "_aws-login")},
+	{"fishCompletion", filepath.Join("bin", "fish", "aws-login.fish")},
+}
+
+func main() {
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "// Code generated by go run scripts/gen_completion.go; DO NOT EDIT.")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "package main")
+	for _, s := range scripts {
+		content, err := ioutil.ReadFile(s.path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if strings.Contains(string(content), "`") {
+			log.Fatalf("%s has backquote, it could not be written as raw string", s.path)
+		}
+		fmt.Fprintf(&buf, "\n// %s is %s\nconst %s = `%s`\n", s.name, filepath.ToSlash(s.path), s.name, content)
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(output, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
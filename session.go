@@ -0,0 +1,86 @@
+package main
+
+import (
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	STSEndpoint          = "sts-endpoint"
+	IAMEndpoint          = "iam-endpoint"
+	STSRegionalEndpoints = "sts-regional-endpoints"
+	UseFIPS              = "use-fips"
+	CABundle             = "ca-bundle"
+	HTTPSProxy           = "https-proxy"
+	Timeout              = "timeout"
+)
+
+// globalClient is set from global flags and environments, profile settings override it.
+var globalClient ClientConfig
+
+var clientFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:    STSEndpoint,
+		Usage:   "custom sts endpoint url, overridden by c_sts_endpoint_url of the profile",
+		EnvVars: []string{"AWS_LOGIN_STS_ENDPOINT"},
+	},
+	&cli.StringFlag{
+		Name:    IAMEndpoint,
+		Usage:   "custom iam endpoint url, overridden by c_iam_endpoint_url of the profile",
+		EnvVars: []string{"AWS_LOGIN_IAM_ENDPOINT"},
+	},
+	&cli.StringFlag{
+		Name:    STSRegionalEndpoints,
+		Usage:   "\"regional\" or \"legacy\", overridden by sts_regional_endpoints of the profile",
+		EnvVars: []string{"AWS_STS_REGIONAL_ENDPOINTS"},
+	},
+	&cli.BoolFlag{
+		Name:    UseFIPS,
+		Usage:   "use fips endpoints, also enabled by use_fips_endpoint of the profile",
+		EnvVars: []string{"AWS_USE_FIPS_ENDPOINT"},
+	},
+	&cli.StringFlag{
+		Name:    CABundle,
+		Usage:   "pem file of certificates to trust, overridden by ca_bundle of the profile",
+		EnvVars: []string{"AWS_CA_BUNDLE"},
+	},
+	&cli.StringFlag{
+		Name:  HTTPSProxy,
+		Usage: "proxy url used for aws api requests, HTTPS_PROXY and NO_PROXY environments are used if not given",
+	},
+	&cli.DurationFlag{
+		Name:    Timeout,
+		Usage:   "timeout of each aws api request, e.g. 30s",
+		EnvVars: []string{"AWS_LOGIN_TIMEOUT"},
+	},
+}
+
+// loadGlobalClientConfig reads global client settings from flags.
+// It is called before actions and at the beginning of completions, as `Before` is skipped when completing.
+func loadGlobalClientConfig(c *cli.Context) {
+	globalClient = ClientConfig{
+		STSEndpoint:          c.String(STSEndpoint),
+		IAMEndpoint:          c.String(IAMEndpoint),
+		STSRegionalEndpoints: c.String(STSRegionalEndpoints),
+		CABundle:             c.String(CABundle),
+		Proxy:                c.String(HTTPSProxy),
+		Timeout:              c.Duration(Timeout),
+	}
+	// fips is left to sdk and profiles unless set by flag or environment
+	if c.IsSet(UseFIPS) {
+		fips := c.Bool(UseFIPS)
+		globalClient.UseFIPS = &fips
+	}
+	if debugLog {
+		globalClient.Logf = debugf
+	}
+}
+
+// clientConfigFor returns global client settings merged with settings of profile in config.
+// Missing profile is not an error, global settings are used.
+func (c *Config) clientConfigFor(profile string) ClientConfig {
+	conf, err := c.LoadConfig(profile)
+	if err != nil {
+		return globalClient
+	}
+	return globalClient.WithProfile(conf)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	Shell = "shell"
+	Wrap  = "wrap"
+
+	ShellBash = "bash"
+	ShellZsh  = "zsh"
+	ShellFish = "fish"
+)
+
+var ShellInitCommand = &cli.Command{
+	Name:      "shell-init",
+	Usage:     "print shell integration, add eval \"$(aws-login shell-init zsh)\" to your rc file",
+	ArgsUsage: "bash|zsh|fish",
+	Action:    shellInitAction,
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  Wrap,
+			Usage: "commands wrapped to login automatically when session of AWS_PROFILE expired, each call checks status first",
+		},
+	},
+}
+
+var UseCommand = &cli.Command{
+	Name:      "use",
+	Usage:     "print commands setting AWS_PROFILE and AWS_REGION of profile, used by awsp of shell-init",
+	ArgsUsage: "<profile>",
+	Action:    useAction,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  Shell,
+			Usage: "one of bash, zsh, fish, detected from SHELL env if not given",
+		},
+	},
+	BashComplete: useBashComplete,
+}
+
+var StatusCommand = &cli.Command{
+	Name:      "status",
+	Usage:     "print session state of profile: valid, expired, no_session or unknown",
+	ArgsUsage: "[profile]",
+	Action:    statusAction,
+}
+
+// shellOf gets shell name from flag, or from SHELL env
+func shellOf(name string) (string, error) {
+	if name == "" {
+		name = filepath.Base(os.Getenv("SHELL"))
+	}
+	switch name {
+	case ShellBash, ShellZsh, ShellFish:
+		return name, nil
+	}
+	return "", fmt.Errorf("shell must be one of bash, zsh, fish, got %q", name)
+}
+
+// quoteFor quotes value as a single quoted string of the shell
+func quoteFor(shell string, value string) string {
+	if shell == ShellFish {
+		value = strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+		return "'" + value + "'"
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// writeUse writes commands setting AWS_PROFILE and region, region is unset if profile has none
+func writeUse(w io.Writer, shell string, profile string, region string) {
+	if shell == ShellFish {
+		fmt.Fprintf(w, "set -gx AWS_PROFILE %s\n", quoteFor(shell, profile))
+		if region == "" {
+			fmt.Fprintln(w, "set -e AWS_REGION AWS_DEFAULT_REGION")
+			return
+		}
+		fmt.Fprintf(w, "set -gx AWS_REGION %s\n", quoteFor(shell, region))
+		fmt.Fprintf(w, "set -gx AWS_DEFAULT_REGION %s\n", quoteFor(shell, region))
+		return
+	}
+	fmt.Fprintf(w, "export AWS_PROFILE=%s\n", quoteFor(shell, profile))
+	if region == "" {
+		fmt.Fprintln(w, "unset AWS_REGION AWS_DEFAULT_REGION")
+		return
+	}
+	fmt.Fprintf(w, "export AWS_REGION=%s\n", quoteFor(shell, region))
+	fmt.Fprintf(w, "export AWS_DEFAULT_REGION=%s\n", quoteFor(shell, region))
+}
+
+func useAction(c *cli.Context) error {
+	profile := c.Args().Get(0)
+	if profile == "" {
+		return fmt.Errorf("profile is required")
+	}
+	shell, err := shellOf(c.String(Shell))
+	if err != nil {
+		return err
+	}
+	config, err := NewConfig(awsFoldPath)
+	if err != nil {
+		return err
+	}
+	section, err := config.loadConfigSection(profile)
+	if err != nil {
+		return &LoginError{Kind: awslogin.ProfileNotFound, Profile: profile, Err: fmt.Errorf("%q %w", profile, awslogin.ErrProfileNotFound)}
+	}
+	writeUse(os.Stdout, shell, profile, section.Key("region").String())
+	return nil
+}
+
+func useBashComplete(c *cli.Context) {
+	loadGlobalClientConfig(c)
+	if c.NArg() > 0 {
+		return
+	}
+	config, err := NewConfig(awsFoldPath)
+	if err != nil {
+		return
+	}
+	for _, p := range config.listManagedProfiles() {
+		printWithExplain(p.Profile, p.Kind+" "+p.Identity)
+	}
+}
+
+// profileStatus finds session state of a profile managed by aws-login
+func profileStatus(config *Config, profile string) (*ProfileInfo, error) {
+	for _, p := range config.listManagedProfiles() {
+		if p.Profile == profile {
+			return &p, nil
+		}
+	}
+	return nil, &LoginError{Kind: awslogin.ProfileNotFound, Profile: profile, Err: fmt.Errorf("%q %w", profile, awslogin.ErrProfileNotFound)}
+}
+
+func statusAction(c *cli.Context) error {
+	profile := c.Args().Get(0)
+	if profile == "" {
+		profile = getProfile(c)
+	}
+	config, err := NewConfig(awsFoldPath)
+	if err != nil {
+		return err
+	}
+	info, err := profileStatus(config, profile)
+	if err != nil {
+		return err
+	}
+	if outputFormat == OutputJSON {
+		printJSON(info)
+		return nil
+	}
+	fmt.Println(info.State)
+	return nil
+}
+
+func shellInitAction(c *cli.Context) error {
+	shell, err := shellOf(c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	writeShellInit(os.Stdout, shell, c.StringSlice(Wrap))
+	return nil
+}
+
+// writeShellInit writes awsp function switching profile, wrappers of commands logging in
+// when session expired, and completion of aws-login
+func writeShellInit(w io.Writer, shell string, wraps []string) {
+	switch shell {
+	case ShellFish:
+		fmt.Fprint(w, fishInit)
+		fmt.Fprint(w, fishCompletion)
+		for _, cmd := range wraps {
+			fmt.Fprintf(w, "function %[1]s --wraps %[1]s\n    _aws_login_ensure; or return $status\n    command %[1]s $argv\nend\n", cmd)
+		}
+	default:
+		fmt.Fprintf(w, posixInit, shell)
+		for _, cmd := range wraps {
+			fmt.Fprintf(w, "%[1]s() {\n  _aws_login_ensure || return $?\n  command %[1]s \"$@\"\n}\n", cmd)
+		}
+		if shell == ShellZsh {
+			fmt.Fprintf(w, "if (( $+functions[compdef] )); then\n%s\nfi\n", strings.TrimSpace(zshCompletion))
+		} else {
+			fmt.Fprint(w, bashCompletion)
+		}
+	}
+}
+
+// posixInit is shared by bash and zsh, formatted with the shell name
+const posixInit = `awsp() {
+  if [ -z "$1" ]; then
+    echo "AWS_PROFILE=${AWS_PROFILE:-default} AWS_REGION=${AWS_REGION}"
+    return
+  fi
+  local init
+  init="$(command aws-login use --shell %[1]s "$1")" || return $?
+  eval "$init"
+}
+
+_aws_login_ensure() {
+  local profile="${AWS_PROFILE:-default}"
+  case "$(command aws-login status "$profile" 2>/dev/null)" in
+    expired|no_session) command aws-login -p "$profile" ;;
+  esac
+}
+`
+
+const fishInit = `function awsp
+    if test (count $argv) -eq 0
+        echo "AWS_PROFILE="(set -q AWS_PROFILE; and echo $AWS_PROFILE; or echo default)" AWS_REGION=$AWS_REGION"
+        return
+    end
+    set -l init (command aws-login use --shell fish $argv[1]); or return $status
+    string join \n -- $init | source
+end
+
+function _aws_login_ensure
+    set -l profile default
+    set -q AWS_PROFILE; and set profile $AWS_PROFILE
+    switch (command aws-login status $profile 2>/dev/null)
+        case expired no_session
+            command aws-login -p $profile
+    end
+end
+`
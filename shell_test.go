@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/sixleaveakkm/aws-login/internal/awstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteUse(t *testing.T) {
+	var buf bytes.Buffer
+	writeUse(&buf, ShellZsh, "it's", "us-east-1")
+	assert.Equal(t, "export AWS_PROFILE='it'\\''s'\nexport AWS_REGION='us-east-1'\nexport AWS_DEFAULT_REGION='us-east-1'\n", buf.String())
+
+	buf.Reset()
+	writeUse(&buf, ShellFish, "dev", "")
+	assert.Equal(t, "set -gx AWS_PROFILE 'dev'\nset -e AWS_REGION AWS_DEFAULT_REGION\n", buf.String())
+}
+
+func TestWriteShellInit(t *testing.T) {
+	var buf bytes.Buffer
+	writeShellInit(&buf, ShellBash, []string{"aws", "terraform"})
+	out := buf.String()
+	assert.Contains(t, out, "aws-login use --shell bash \"$1\"")
+	assert.Contains(t, out, "terraform() {\n  _aws_login_ensure || return $?\n  command terraform \"$@\"\n}")
+	assert.Contains(t, out, "complete -o bashdefault")
+
+	buf.Reset()
+	writeShellInit(&buf, ShellFish, []string{"aws"})
+	assert.Contains(t, buf.String(), "function aws --wraps aws")
+
+	// status is checked before wrapped commands, nothing is wrapped unless asked
+	out = captureStdout(t, func() { executor([]string{"aws-login", "shell-init", "bash"}) })
+	assert.Contains(t, out, "awsp()")
+	assert.NotContains(t, out, "_aws_login_ensure ||")
+}
+
+func TestProfileStatus(t *testing.T) {
+	dir := awstest.WriteFolder(t, `
+[profile dev]
+mfa_serial = arn:aws:iam::123456789012:mfa/user
+`, `
+[dev]
+aws_access_key_id = SESSION_KEY
+aws_secret_access_key = SESSION_SECRET
+aws_session_token = TOKEN
+aws_expiration = 2000-01-01T00:00:00Z
+`)
+	defer os.RemoveAll(dir)
+
+	config := mustNewConfig(t, dir)
+	info, err := profileStatus(config, "dev")
+	assert.NoError(t, err)
+	assert.Equal(t, StateExpired, info.State)
+
+	_, err = profileStatus(config, "missing")
+	assert.Equal(t, ExitProfileNotFound, exitCode(err))
+}
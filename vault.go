@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	VaultRole      = "role"
+	ServerIDHeader = "server-id-header"
+	VaultLogin     = "login"
+	VaultAddr      = "vault-addr"
+	VaultMount     = "vault-mount"
+	VaultNamespace = "vault-namespace"
+	VaultSTS       = "vault-sts-endpoint"
+)
+
+var VaultPayloadCommand = &cli.Command{
+	Name:   "vault-payload",
+	Usage:  "print login body of vault aws auth signed with session of profile, or login to vault with it",
+	Action: vaultPayloadAction,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    Profile,
+			Aliases: []string{"p"},
+			Usage:   "profile whose session signs the payload",
+		},
+		&cli.StringFlag{
+			Name:  VaultRole,
+			Usage: "vault role to login, the default role of vault if not given",
+		},
+		&cli.StringFlag{
+			Name:    ServerIDHeader,
+			Usage:   "value of X-Vault-AWS-IAM-Server-ID header, same as iam_server_id_header_value of vault",
+			EnvVars: []string{"AWS_LOGIN_VAULT_SERVER_ID"},
+		},
+		&cli.StringFlag{
+			Name:  Region,
+			Usage: "region of sts request, global endpoint (us-east-1) vault verifies by default if not given",
+		},
+		&cli.StringFlag{
+			Name:  VaultSTS,
+			Usage: "sts endpoint url vault verifies with, same as sts_endpoint of vault, endpoint of region if not given",
+		},
+		&cli.BoolFlag{
+			Name:  VaultLogin,
+			Usage: "post payload to vault and print client token instead of payload",
+		},
+		&cli.StringFlag{
+			Name:    VaultAddr,
+			Usage:   "address of vault",
+			EnvVars: []string{"VAULT_ADDR"},
+		},
+		&cli.StringFlag{
+			Name:  VaultMount,
+			Usage: "path aws auth method is mounted at",
+			Value: "aws",
+		},
+		&cli.StringFlag{
+			Name:    VaultNamespace,
+			Usage:   "vault namespace",
+			EnvVars: []string{"VAULT_NAMESPACE"},
+		},
+	},
+}
+
+// VaultAuth is the auth of vault login response
+type VaultAuth struct {
+	ClientToken   string   `json:"client_token"`
+	Accessor      string   `json:"accessor"`
+	Policies      []string `json:"policies"`
+	LeaseDuration int64    `json:"lease_duration"`
+	Renewable     bool     `json:"renewable"`
+}
+
+// vaultLogin posts payload to login endpoint of aws auth mounted at mount
+func vaultLogin(httpClient *http.Client, addr string, mount string, namespace string, payload *awslogin.VaultLoginPayload) (*VaultAuth, error) {
+	body, _ := json.Marshal(payload)
+	u := fmt.Sprintf("%s/v1/auth/%s/login", strings.TrimSuffix(addr, "/"), strings.Trim(mount, "/"))
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+	debugf("logging in vault at %s", u)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Auth   *VaultAuth `json:"auth"`
+		Errors []string   `json:"errors"`
+	}
+	_ = json.Unmarshal(content, &result)
+	if resp.StatusCode != http.StatusOK {
+		if len(result.Errors) > 0 {
+			return nil, fmt.Errorf("vault returned %s, %s", resp.Status, strings.Join(result.Errors, ", "))
+		}
+		return nil, fmt.Errorf("vault returned %s", resp.Status)
+	}
+	if result.Auth == nil || result.Auth.ClientToken == "" {
+		return nil, fmt.Errorf("vault returned no client token")
+	}
+	return result.Auth, nil
+}
+
+func vaultPayloadAction(c *cli.Context) error {
+	profile := c.String(Profile)
+	if profile == "" {
+		profile = getProfile(c)
+	}
+	config, err := NewConfig(awsFoldPath)
+	if err != nil {
+		return err
+	}
+	data, err := loadExportData(config, profile)
+	if err != nil {
+		return err
+	}
+	// endpoint settings of profile are not used, vault verifies with the endpoint it is configured
+	signer := ClientConfig{Region: c.String(Region), STSEndpoint: c.String(VaultSTS), Logf: globalClient.Logf}
+	payload, err := awslogin.NewVaultLoginPayload(data.Credential, c.String(VaultRole), c.String(ServerIDHeader), signer)
+	if err != nil {
+		return err
+	}
+
+	if !c.Bool(VaultLogin) {
+		content, _ := json.Marshal(payload)
+		fmt.Println(string(content))
+		return nil
+	}
+	addr := c.String(VaultAddr)
+	if addr == "" {
+		return fmt.Errorf("vault address is required to login, set --%s or VAULT_ADDR", VaultAddr)
+	}
+	// ca bundle and proxy of profile are used, e.g. of a tls-inspecting proxy in front of vault
+	httpClient, err := awslogin.NewHTTPClient(config.clientConfigFor(profile))
+	if err != nil {
+		return err
+	}
+	auth, err := vaultLogin(httpClient, addr, c.String(VaultMount), c.String(VaultNamespace), payload)
+	if err != nil {
+		return fmt.Errorf("failed to login vault, %w", err)
+	}
+	if outputFormat == OutputJSON {
+		printJSON(auth)
+		return nil
+	}
+	fmt.Println(auth.ClientToken)
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sixleaveakkm/aws-login/internal/awstest"
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVaultLogin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/auth/aws-prod/login", r.URL.Path)
+		assert.Equal(t, "team", r.Header.Get("X-Vault-Namespace"))
+		var payload awslogin.VaultLoginPayload
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		if payload.Role != "dev" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors":["entry for role \"admin\" not found"]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"auth":{"client_token":"s.TOKEN","policies":["default"],"lease_duration":3600}}`))
+	}))
+	defer server.Close()
+
+	auth, err := vaultLogin(server.Client(), server.URL+"/", "/aws-prod/", "team", &awslogin.VaultLoginPayload{Role: "dev"})
+	assert.NoError(t, err)
+	assert.Equal(t, "s.TOKEN", auth.ClientToken)
+	assert.Equal(t, int64(3600), auth.LeaseDuration)
+
+	_, err = vaultLogin(server.Client(), server.URL, "aws-prod", "team", &awslogin.VaultLoginPayload{Role: "admin"})
+	assert.EqualError(t, err, `vault returned 400 Bad Request, entry for role "admin" not found`)
+}
+
+func TestVaultPayloadLoginCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"auth":{"client_token":"s.TOKEN"}}`))
+	}))
+	defer server.Close()
+
+	valid := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	dir := awstest.WriteFolder(t, "", "[dev]\naws_access_key_id = KEY\naws_secret_access_key = SECRET\naws_session_token = TOKEN\naws_expiration = "+valid+"\n")
+	defer os.RemoveAll(dir)
+	bundle := filepath.Join(dir, "ca.pem")
+	content := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.NoError(t, ioutil.WriteFile(bundle, content, 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, configFile_), []byte("[profile dev]\nca_bundle = "+bundle+"\n"), 0600))
+	originalFolder := awsFoldPath
+	awsFoldPath = dir
+	defer func() { awsFoldPath = originalFolder }()
+
+	out := captureStdout(t, func() {
+		executor([]string{"aws-login", "vault-payload", "-p", "dev", "--login", "--vault-addr", server.URL})
+	})
+	assert.Equal(t, "s.TOKEN\n", out)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sixleaveakkm/aws-login/pkg/awslogin"
+	"github.com/urfave/cli/v2"
+)
+
+const NoAlias = "no-alias"
+
+const (
+	MFAStateYes     = "yes"
+	MFAStateNo      = "no"
+	MFAStateUnknown = "unknown"
+)
+
+var WhoamiCommand = &cli.Command{
+	Name:   "whoami",
+	Usage:  "show account, arn, session expiry and mfa state of profile",
+	Action: whoamiAction,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    Profile,
+			Aliases: []string{"p"},
+			Usage:   "profile to show",
+		},
+		&cli.BoolFlag{
+			Name:  NoAlias,
+			Usage: "don't list account alias, which requires iam:ListAccountAliases",
+		},
+	},
+}
+
+// IdentityCache is identity of session, saved next to it in credentials by whoami,
+// so list and completion show it without calling aws.
+type IdentityCache struct {
+	AccountID    string `ini:"c_account_id,omitempty"`
+	Arn          string `ini:"c_arn,omitempty"`
+	AccountAlias string `ini:"c_account_alias,omitempty"`
+}
+
+// WhoamiResult is printed by whoami
+type WhoamiResult struct {
+	Profile      string     `json:"profile"`
+	Account      string     `json:"account"`
+	AccountAlias string     `json:"account_alias,omitempty"`
+	Arn          string     `json:"arn"`
+	UserID       string     `json:"user_id"`
+	SessionName  string     `json:"session_name,omitempty"`
+	Expiry       *time.Time `json:"expiry,omitempty"`
+	MFA          string     `json:"mfa"`
+}
+
+// sessionNameFromArn gets session name from arn like "arn:aws:sts::123456789012:assumed-role/admin/cli"
+func sessionNameFromArn(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 || !strings.HasPrefix(parts[5], "assumed-role/") {
+		return ""
+	}
+	resource := strings.Split(parts[5], "/")
+	return resource[len(resource)-1]
+}
+
+// loadIdentityCache gets identity saved by whoami, empty if never saved
+func (c *Config) loadIdentityCache(profile string) IdentityCache {
+	var identity IdentityCache
+	if section, err := c.Cred.GetSection(profile); err == nil {
+		_ = section.MapTo(&identity)
+	}
+	return identity
+}
+
+// saveIdentityCache saves identity next to session of profile, nothing is saved if profile has no credential section
+func (c *Config) saveIdentityCache(profile string, identity *CallerIdentity) {
+	section, err := c.Cred.GetSection(profile)
+	if err != nil {
+		return
+	}
+	for key, value := range map[string]string{
+		"c_account_id":    identity.Account,
+		"c_arn":           identity.Arn,
+		"c_account_alias": identity.Alias,
+	} {
+		if value == "" {
+			section.DeleteKey(key)
+		} else {
+			section.Key(key).SetValue(value)
+		}
+	}
+	if err := c.Save(c.Cred, credentialsFile_); err != nil {
+		debugf("failed to save identity of %q, %v", profile, err)
+	}
+}
+
+// mfaState tells whether session of profile is authenticated with mfa
+func (c *Config) mfaState(profile string) string {
+	if conf, err := c.LoadConfig(profile); err == nil && conf.SerialNumber != "" {
+		return MFAStateYes
+	}
+	if section, err := c.Cred.GetSection(profile); err == nil && !section.HasKey("aws_session_token") {
+		return MFAStateNo
+	}
+	return MFAStateUnknown
+}
+
+// displayAccount shows account with its alias if known
+func displayAccount(account string, alias string) string {
+	if alias == "" {
+		return account
+	}
+	return fmt.Sprintf("%s (%s)", account, alias)
+}
+
+func whoamiAction(c *cli.Context) error {
+	profile := c.String(Profile)
+	if profile == "" {
+		profile = getProfile(c)
+	}
+	config, err := NewConfig(awsFoldPath)
+	if err != nil {
+		return err
+	}
+	identity, err := aws.GetCallerIdentity(profile, config.clientConfigFor(profile), !c.Bool(NoAlias))
+	if err != nil {
+		return awslogin.NewLoginError(profile, "failed to get caller identity", err, false)
+	}
+	config.saveIdentityCache(profile, identity)
+
+	result := &WhoamiResult{
+		Profile:      profile,
+		Account:      identity.Account,
+		AccountAlias: identity.Alias,
+		Arn:          identity.Arn,
+		UserID:       identity.UserID,
+		SessionName:  sessionNameFromArn(identity.Arn),
+		MFA:          config.mfaState(profile),
+	}
+	if cred, err := config.LoadSession(profile); err == nil && !cred.Expiration.IsZero() {
+		result.Expiry = &cred.Expiration
+	}
+
+	if outputFormat == OutputJSON {
+		printJSON(result)
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "profile\t%s\n", result.Profile)
+	fmt.Fprintf(w, "account\t%s\n", displayAccount(result.Account, result.AccountAlias))
+	fmt.Fprintf(w, "arn\t%s\n", result.Arn)
+	if result.SessionName != "" {
+		fmt.Fprintf(w, "session\t%s\n", result.SessionName)
+	}
+	if result.Expiry != nil {
+		fmt.Fprintf(w, "expiry\t%s (%s left)\n", result.Expiry.Local().Format(time.RFC3339), time.Until(*result.Expiry).Round(time.Minute))
+	}
+	fmt.Fprintf(w, "mfa\t%s\n", result.MFA)
+	return w.Flush()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/sixleaveakkm/aws-login/internal/awstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionNameFromArn(t *testing.T) {
+	assert.Equal(t, "cli", sessionNameFromArn("arn:aws:sts::123456789012:assumed-role/admin/cli"))
+	assert.Equal(t, "", sessionNameFromArn("arn:aws:iam::123456789012:user/alice"))
+	assert.Equal(t, "", sessionNameFromArn("not an arn"))
+}
+
+func TestIdentityCache(t *testing.T) {
+	dir := awstest.WriteFolder(t, `
+[profile dev]
+mfa_serial = arn:aws:iam::123456789012:mfa/user
+`, `
+[dev_no_mfa]
+aws_access_key_id = KEY
+aws_secret_access_key = SECRET
+
+[dev]
+aws_access_key_id = SESSION_KEY
+aws_secret_access_key = SESSION_SECRET
+aws_session_token = TOKEN
+c_account_alias = old-alias
+`)
+	defer os.RemoveAll(dir)
+	_, restore := setOutputFolder(t)
+	defer restore()
+
+	config := mustNewConfig(t, dir)
+	config.saveIdentityCache("dev", &CallerIdentity{Account: "123456789012", Arn: "arn:aws:iam::123456789012:user/alice"})
+	// nothing is saved for profile without credential
+	config.saveIdentityCache("missing", &CallerIdentity{Account: "123456789012"})
+
+	profiles := config.listManagedProfiles()
+	assert.Len(t, profiles, 1)
+	assert.Equal(t, "123456789012", profiles[0].Account)
+	assert.Equal(t, "", profiles[0].AccountAlias)
+	_, err := config.Cred.GetSection("missing")
+	assert.Error(t, err)
+
+	assert.Equal(t, MFAStateYes, config.mfaState("dev"))
+	assert.Equal(t, MFAStateNo, config.mfaState("dev_no_mfa"))
+}